@@ -32,12 +32,14 @@ class InvalidEntitiesDetected(Exception):
 
 
 def address(public_key):
-    return subprocess.check_output([
-        '/tmp/oasis-node',
-        'stake',
-        'pubkey2address',
-        '--public_key', public_key
-    ])
+    output = subprocess.check_output([
+        '/tmp/genesis-tools',
+        'address',
+        'convert',
+        '--address.format', 'plain',
+        public_key,
+    ]).decode('utf-8')
+    return output.strip()
 
 
 class EntityPackage(object):
@@ -124,7 +126,7 @@ class EntityPackage(object):
     def address(self):
         if self._entity_descriptor is None:
             return "unknown_entity_package_invalid"
-        return address(base64.b64encode(self._entity_descriptor["id"])).decode('utf-8').strip()
+        return address(base64.b64encode(self._entity_descriptor["id"]).decode('utf-8'))
 
     @property
     def entity_id(self):
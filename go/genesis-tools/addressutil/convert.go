@@ -0,0 +1,225 @@
+// Package addressutil converts between public keys and staking addresses.
+package addressutil
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// Kinds of inputs a Conversion is made from.
+const (
+	KindBase64Key = "base64 key"
+	KindHexKey    = "hex key"
+	KindAddress   = "address"
+)
+
+// Formats conversions can be written in.
+const (
+	// FormatTable is a table with a header row, for humans.
+	FormatTable = "table"
+	// FormatPlain is one address per line, in the order of the inputs.
+	FormatPlain = "plain"
+	// FormatJSON is a json list of the conversions.
+	FormatJSON = "json"
+)
+
+// addressPrefix is the human readable part of staking addresses, with the
+// bech32 separator.
+const addressPrefix = "oasis1"
+
+// Conversion is a public key or address in every supported form.
+type Conversion struct {
+	Input string
+	Kind  string
+	// PublicKey is nil if the input is an address, an address cannot be
+	// converted back to a key.
+	PublicKey *signature.PublicKey
+	Address   staking.Address
+}
+
+// PublicKeyHex returns the public key in hex, or an empty string if it is
+// unknown.
+func (c *Conversion) PublicKeyHex() string {
+	if c.PublicKey == nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", c.PublicKey[:])
+}
+
+// PublicKeyBase64 returns the public key in base64, or an empty string if it
+// is unknown.
+func (c *Conversion) PublicKeyBase64() string {
+	if c.PublicKey == nil {
+		return ""
+	}
+	return c.PublicKey.String()
+}
+
+// Convert parses a base64 or hex public key, the hex key optionally prefixed
+// with 0x, or an oasis1 address.
+func Convert(input string) (*Conversion, error) {
+	input = strings.TrimSpace(input)
+	c := &Conversion{Input: input}
+
+	lower := strings.ToLower(input)
+	switch {
+	case input == "":
+		return nil, errors.New("empty key or address")
+	case strings.HasPrefix(lower, addressPrefix):
+		c.Kind = KindAddress
+		if err := c.Address.UnmarshalText([]byte(lower)); err != nil {
+			return nil, fmt.Errorf("malformed address %q: %w", input, err)
+		}
+		if !c.Address.IsValid() {
+			return nil, fmt.Errorf("invalid address %q", input)
+		}
+		return c, nil
+	case len(strings.TrimPrefix(lower, "0x")) == 2*signature.PublicKeySize:
+		c.Kind = KindHexKey
+		c.PublicKey = new(signature.PublicKey)
+		if err := c.PublicKey.UnmarshalHex(strings.TrimPrefix(lower, "0x")); err != nil {
+			return nil, fmt.Errorf("malformed hex key %q: %w", input, err)
+		}
+	default:
+		c.Kind = KindBase64Key
+		c.PublicKey = new(signature.PublicKey)
+		if err := c.PublicKey.UnmarshalText([]byte(input)); err != nil {
+			return nil, fmt.Errorf("malformed key or address %q: %w", input, err)
+		}
+	}
+
+	if !c.PublicKey.IsValid() {
+		return nil, fmt.Errorf("invalid public key %q", input)
+	}
+	c.Address = staking.NewAddress(*c.PublicKey)
+	return c, nil
+}
+
+// conversionJSON is the json form of a Conversion.
+type conversionJSON struct {
+	Input        string          `json:"input"`
+	Kind         string          `json:"kind"`
+	PublicKey    string          `json:"public_key,omitempty"`
+	PublicKeyHex string          `json:"public_key_hex,omitempty"`
+	Address      staking.Address `json:"address"`
+}
+
+// WriteConversions writes the conversions in one of the formats.
+func WriteConversions(w io.Writer, conversions []*Conversion, format string) error {
+	switch format {
+	case FormatTable:
+		return writeConversionsTable(w, conversions)
+	case FormatPlain:
+		for _, c := range conversions {
+			if _, err := fmt.Fprintln(w, c.Address); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		out := make([]conversionJSON, 0, len(conversions))
+		for _, c := range conversions {
+			out = append(out, conversionJSON{
+				Input:        c.Input,
+				Kind:         c.Kind,
+				PublicKey:    c.PublicKeyBase64(),
+				PublicKeyHex: c.PublicKeyHex(),
+				Address:      c.Address,
+			})
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	default:
+		return fmt.Errorf("unknown format %q, expected %s, %s or %s", format, FormatTable, FormatPlain, FormatJSON)
+	}
+}
+
+func writeConversionsTable(w io.Writer, conversions []*Conversion) error {
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "input\tkind\tpublic key\tpublic key hex\taddress")
+	for _, c := range conversions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			c.Input, c.Kind, dash(c.PublicKeyBase64()), dash(c.PublicKeyHex()), c.Address)
+	}
+	return tw.Flush()
+}
+
+// ConvertCSV converts the values of a column of a csv with a header row. The
+// rows are written back with the public key, public key hex, address and
+// error columns appended, so that a malformed value does not stop the
+// conversion. Rows with more fields than the header are written up to the
+// header and fail, rather than shifting the appended columns. It returns the
+// number of rows that could not be converted.
+func ConvertCSV(r io.Reader, w io.Writer, column string) (int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return 0, errors.New("missing header row")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	index := -1
+	for i, label := range header {
+		if strings.TrimSpace(label) == column {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return 0, fmt.Errorf("missing column %q", column)
+	}
+
+	writer := csv.NewWriter(w)
+	if err = writer.Write(append(header, "public_key", "public_key_hex", "address", "error")); err != nil {
+		return 0, err
+	}
+	failed := 0
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return failed, err
+		}
+
+		out := make([]string, len(header))
+		copy(out, record)
+		if len(record) > len(header) {
+			failed++
+			out = append(out, "", "", "", fmt.Sprintf("row %d: %d fields, the header has %d", row, len(record), len(header)))
+		} else if index >= len(record) {
+			failed++
+			out = append(out, "", "", "", fmt.Sprintf("row %d: missing column %q", row, column))
+		} else if c, err := Convert(record[index]); err != nil {
+			failed++
+			out = append(out, "", "", "", err.Error())
+		} else {
+			out = append(out, c.PublicKeyBase64(), c.PublicKeyHex(), c.Address.String(), "")
+		}
+		if err = writer.Write(out); err != nil {
+			return failed, err
+		}
+	}
+	writer.Flush()
+	return failed, writer.Error()
+}
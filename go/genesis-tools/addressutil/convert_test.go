@@ -0,0 +1,108 @@
+package addressutil_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/addressutil"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+func TestConvert(t *testing.T) {
+	pub := memorySigner.NewTestSigner("addressutil").Public()
+	address := staking.NewAddress(pub)
+	hexKey := hex.EncodeToString(pub[:])
+
+	for _, input := range []string{pub.String(), hexKey, "0x" + strings.ToUpper(hexKey), " " + pub.String() + "\n"} {
+		c, err := addressutil.Convert(input)
+		require.NoError(t, err, input)
+		require.Equal(t, address, c.Address, input)
+		require.Equal(t, pub.String(), c.PublicKeyBase64(), input)
+		require.Equal(t, hexKey, c.PublicKeyHex(), input)
+	}
+
+	c, err := addressutil.Convert(address.String())
+	require.NoError(t, err)
+	require.Equal(t, addressutil.KindAddress, c.Kind)
+	require.Equal(t, address, c.Address)
+	require.Nil(t, c.PublicKey)
+	require.Empty(t, c.PublicKeyHex())
+
+	for _, input := range []string{
+		"",
+		"oasis1qz2kz3zkgf6trclyajtyg4jecw7es7p5tutfqaz1",
+		"oasis1",
+		hexKey[2:] + "zz",
+		pub.String()[4:],
+		"not a key",
+	} {
+		_, err = addressutil.Convert(input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestConvertCSV(t *testing.T) {
+	pub := memorySigner.NewTestSigner("addressutil").Public()
+	input := "name,public_key\n" +
+		"a," + pub.String() + "\n" +
+		"b,garbage\n" +
+		"c\n" +
+		"d," + pub.String() + ",extra\n"
+
+	var out bytes.Buffer
+	failed, err := addressutil.ConvertCSV(strings.NewReader(input), &out, "public_key")
+	require.NoError(t, err)
+	require.Equal(t, 3, failed)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 5)
+	require.Equal(t, "name,public_key,public_key,public_key_hex,address,error", lines[0])
+	require.True(t, strings.HasSuffix(lines[1], ","+staking.NewAddress(pub).String()+","), lines[1])
+	require.Contains(t, lines[2], "malformed key or address")
+	require.Contains(t, lines[3], `row 4: missing column`)
+	require.Equal(t, "d,"+pub.String()+",,,,\"row 5: 3 fields, the header has 2\"", lines[4])
+
+	_, err = addressutil.ConvertCSV(strings.NewReader("name,key\n"), &out, "public_key")
+	require.Error(t, err)
+	_, err = addressutil.ConvertCSV(strings.NewReader(""), &out, "public_key")
+	require.Error(t, err)
+}
+
+func TestWriteConversions(t *testing.T) {
+	pub := memorySigner.NewTestSigner("addressutil").Public()
+	address := staking.NewAddress(pub)
+	c, err := addressutil.Convert(pub.String())
+	require.NoError(t, err)
+	conversions := []*addressutil.Conversion{c, c}
+
+	var out bytes.Buffer
+	require.NoError(t, addressutil.WriteConversions(&out, conversions, addressutil.FormatPlain))
+	require.Equal(t, address.String()+"\n"+address.String()+"\n", out.String())
+
+	out.Reset()
+	require.NoError(t, addressutil.WriteConversions(&out, conversions, addressutil.FormatJSON))
+	var decoded []map[string]string
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded, 2)
+	require.Equal(t, map[string]string{
+		"input":          pub.String(),
+		"kind":           c.Kind,
+		"public_key":     pub.String(),
+		"public_key_hex": c.PublicKeyHex(),
+		"address":        address.String(),
+	}, decoded[0])
+
+	out.Reset()
+	require.NoError(t, addressutil.WriteConversions(&out, conversions, addressutil.FormatTable))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.True(t, strings.HasSuffix(lines[1], address.String()), lines[1])
+
+	require.Error(t, addressutil.WriteConversions(&out, conversions, "yaml"))
+}
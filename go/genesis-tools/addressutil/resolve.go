@@ -0,0 +1,106 @@
+package addressutil
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// NamedAddress is the address of an entity or an account of the staking
+// configuration.
+type NamedAddress struct {
+	Name string
+	// EntityID is nil for accounts of the staking configuration.
+	EntityID *signature.PublicKey
+	Address  staking.Address
+}
+
+// ResolveEntities returns the addresses of the named entities, or of every
+// entity if no names are given, ordered by name.
+func ResolveEntities(entities stakinggenesis.Entities, names []string) ([]*NamedAddress, error) {
+	if len(names) == 0 {
+		for name := range entities.All() {
+			names = append(names, name)
+		}
+	}
+
+	var resolved []*NamedAddress
+	var unknown []string
+	for _, name := range names {
+		ent := entities.ResolveEntity(strings.ToLower(name))
+		if ent == nil {
+			unknown = append(unknown, name)
+			continue
+		}
+		id := ent.ID
+		resolved = append(resolved, &NamedAddress{
+			Name:     strings.ToLower(name),
+			EntityID: &id,
+			Address:  staking.NewAddress(id),
+		})
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown entities: %s", strings.Join(unknown, ", "))
+	}
+
+	sort.Slice(resolved, func(i, j int) bool {
+		return resolved[i].Name < resolved[j].Name
+	})
+	return resolved, nil
+}
+
+// CheckConfigAddresses returns the addresses of the accounts of a loaded
+// staking configuration, ordered by name. The configuration already rejects
+// malformed and duplicate addresses, this also rejects reserved addresses
+// and addresses of entities, which would be funded twice. entities may be
+// nil.
+func CheckConfigAddresses(config *stakinggenesis.GenesisConfig, entities stakinggenesis.Entities) ([]*NamedAddress, error) {
+	entityNames := make(map[staking.Address]string)
+	if entities != nil {
+		for name, ent := range entities.All() {
+			entityNames[staking.NewAddress(ent.ID)] = name
+		}
+	}
+
+	var accounts []*NamedAddress
+	var problems []string
+	for name, account := range config.Accounts {
+		address := account.Address()
+		if address.IsReserved() {
+			problems = append(problems, fmt.Sprintf("account %s: address %s is reserved", name, address))
+		}
+		if entityName, ok := entityNames[address]; ok {
+			problems = append(problems, fmt.Sprintf("account %s: address %s is the address of entity %s", name, address, entityName))
+		}
+		accounts = append(accounts, &NamedAddress{Name: name, Address: address})
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return accounts, fmt.Errorf("%d problem(s) with the account addresses: %s", len(problems), strings.Join(problems, "; "))
+	}
+	return accounts, nil
+}
+
+// WriteNamedAddresses writes the addresses as a table.
+func WriteNamedAddresses(w io.Writer, addresses []*NamedAddress) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "name\tentity id\taddress")
+	for _, a := range addresses {
+		id := "-"
+		if a.EntityID != nil {
+			id = a.EntityID.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Name, id, a.Address)
+	}
+	return tw.Flush()
+}
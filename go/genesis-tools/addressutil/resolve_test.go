@@ -0,0 +1,99 @@
+package addressutil_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/addressutil"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+const configFixturePath = "../stakinggenesis/fixtures/staking_ledger_config.yaml"
+
+type testEntities map[string]*entity.Entity
+
+func newTestEntities(ids map[string]signature.PublicKey) testEntities {
+	entities := make(testEntities)
+	for name, id := range ids {
+		entities[name] = &entity.Entity{
+			Versioned: cbor.NewVersioned(entity.LatestEntityDescriptorVersion),
+			ID:        id,
+		}
+	}
+	return entities
+}
+
+func (e testEntities) All() map[string]*entity.Entity {
+	return e
+}
+
+func (e testEntities) ResolveEntity(name string) *entity.Entity {
+	return e[name]
+}
+
+func TestResolveEntities(t *testing.T) {
+	entities := newTestEntities(map[string]signature.PublicKey{
+		"test1": memorySigner.NewTestSigner("test1").Public(),
+		"test2": memorySigner.NewTestSigner("test2").Public(),
+	})
+
+	resolved, err := addressutil.ResolveEntities(entities, nil)
+	require.NoError(t, err)
+	require.Len(t, resolved, 2)
+	require.Equal(t, "test1", resolved[0].Name)
+	require.Equal(t, staking.NewAddress(memorySigner.NewTestSigner("test1").Public()), resolved[0].Address)
+
+	resolved, err = addressutil.ResolveEntities(entities, []string{"Test2"})
+	require.NoError(t, err)
+	require.Len(t, resolved, 1)
+	require.Equal(t, memorySigner.NewTestSigner("test2").Public(), *resolved[0].EntityID)
+
+	_, err = addressutil.ResolveEntities(entities, []string{"test2", "test3"})
+	require.EqualError(t, err, "unknown entities: test3")
+}
+
+func TestCheckConfigAddresses(t *testing.T) {
+	config, err := stakinggenesis.LoadGenesisConfig(configFixturePath)
+	require.NoError(t, err)
+
+	accounts, err := addressutil.CheckConfigAddresses(config, nil)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Equal(t, "account1", accounts[0].Name)
+	require.Equal(t, "oasis1qz2kz3zkgf6trclyajtyg4jecw7es7p5tutfqaz0", accounts[0].Address.String())
+
+	var buf bytes.Buffer
+	require.NoError(t, addressutil.WriteNamedAddresses(&buf, accounts))
+	require.Contains(t, buf.String(), "account2  -          oasis1qz6hdmtth24x5udlvmavufwvy5ac6pvh2cdlehnx")
+
+	// An account with the address of an entity would be funded twice.
+	entityID := memorySigner.NewTestSigner("test1").Public()
+	b, err := ioutil.ReadFile(configFixturePath)
+	require.NoError(t, err)
+	b = bytes.Replace(b, []byte(accounts[0].Address.String()), []byte(staking.NewAddress(entityID).String()), 1)
+	dir, err := ioutil.TempDir("", "addressutil")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "staking_config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, b, 0o644))
+	config, err = stakinggenesis.LoadGenesisConfig(path)
+	require.NoError(t, err)
+
+	entities := newTestEntities(map[string]signature.PublicKey{"test1": entityID})
+	_, err = addressutil.CheckConfigAddresses(config, entities)
+	require.EqualError(t, err, fmt.Sprintf(
+		"1 problem(s) with the account addresses: account account1: address %s is the address of entity test1",
+		staking.NewAddress(entityID),
+	))
+}
@@ -0,0 +1,169 @@
+package approval_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/approval"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
+)
+
+const stakingGenesisPath = "../stakinggenesis/testdata/staking_genesis.json"
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "approval")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// writeApprovers writes an approvers file of the signers and returns its
+// path.
+func writeApprovers(t *testing.T, threshold int, signers map[string]signature.Signer) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "threshold: %d\napprovers:\n", threshold)
+	for name, signer := range signers {
+		fmt.Fprintf(&b, "  %s: %q\n", name, signer.Public())
+	}
+	path := filepath.Join(tempDir(t), "approvers.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(b.String()), 0o644))
+	return path
+}
+
+func TestLoadTarget(t *testing.T) {
+	target, err := approval.LoadTarget(stakingGenesisPath)
+	require.NoError(t, err)
+	require.Equal(t, approval.KindStaking, target.Kind)
+
+	// The hash does not depend on the json formatting.
+	b, err := ioutil.ReadFile(stakingGenesisPath)
+	require.NoError(t, err)
+	var compacted bytes.Buffer
+	require.NoError(t, json.Compact(&compacted, b))
+	path := filepath.Join(tempDir(t), "staking.json")
+	require.NoError(t, ioutil.WriteFile(path, compacted.Bytes(), 0o644))
+	compact, err := approval.LoadTarget(path)
+	require.NoError(t, err)
+	require.Equal(t, target, compact)
+
+	doc := genesis.Document{Height: 1, Time: time.Unix(1605000000, 0).UTC(), ChainID: "test"}
+	b, err = json.Marshal(&doc)
+	require.NoError(t, err)
+	path = filepath.Join(tempDir(t), "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, b, 0o644))
+	target, err = approval.LoadTarget(path)
+	require.NoError(t, err)
+	require.Equal(t, approval.KindGenesis, target.Kind)
+	require.Equal(t, doc.Hash(), target.Hash)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	target, err := approval.LoadTarget(stakingGenesisPath)
+	require.NoError(t, err)
+	signers := map[string]signature.Signer{
+		"alice": memorySigner.NewTestSigner("alice"),
+		"bob":   memorySigner.NewTestSigner("bob"),
+		"carol": memorySigner.NewTestSigner("carol"),
+	}
+	approvers, err := approval.LoadApprovers(writeApprovers(t, 2, signers))
+	require.NoError(t, err)
+
+	manifest := approval.NewManifest(target)
+	require.NoError(t, manifest.Sign(target, signers["alice"], "alice"))
+	require.NoError(t, manifest.Sign(target, memorySigner.NewTestSigner("mallory"), "mallory"))
+
+	// The manifest survives a round trip.
+	path := filepath.Join(tempDir(t), "manifest.json")
+	require.NoError(t, manifest.Write(path))
+	manifest, err = approval.LoadManifest(path)
+	require.NoError(t, err)
+
+	result, err := approval.Verify(manifest, target, approvers)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Approved)
+	require.Len(t, result.Unknown, 1)
+	require.EqualError(t, result.Check(), "1 of 2 required approvals")
+
+	// Signing twice replaces the approval.
+	require.NoError(t, manifest.Sign(target, signers["bob"], "bob"))
+	require.NoError(t, manifest.Sign(target, signers["bob"], "bob"))
+	require.Len(t, manifest.Approvals, 3)
+	result, err = approval.Verify(manifest, target, approvers)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Approved)
+	require.NoError(t, result.Check())
+	require.Equal(t, approval.StatusMissing, result.Approvers[2].Status)
+
+	var report strings.Builder
+	require.NoError(t, approval.WriteResult(&report, result))
+	require.Contains(t, report.String(), "2 of 2 required approvals")
+	require.Contains(t, report.String(), "not an approver")
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	target, err := approval.LoadTarget(stakingGenesisPath)
+	require.NoError(t, err)
+	signers := map[string]signature.Signer{
+		"alice": memorySigner.NewTestSigner("alice"),
+		"bob":   memorySigner.NewTestSigner("bob"),
+	}
+	approvers, err := approval.LoadApprovers(writeApprovers(t, 1, signers))
+	require.NoError(t, err)
+
+	manifest := approval.NewManifest(target)
+	require.NoError(t, manifest.Sign(target, signers["alice"], "alice"))
+	require.NoError(t, manifest.Sign(target, signers["bob"], "bob"))
+
+	// A signature of another genesis is invalid, even with the threshold
+	// met by the other approver.
+	other := *target
+	other.Hash.FromBytes([]byte("another genesis"))
+	forged := approval.NewManifest(&other)
+	require.NoError(t, forged.Sign(&other, signers["bob"], "bob"))
+	for i, a := range manifest.Approvals {
+		if a.PublicKey.Equal(signers["bob"].Public()) {
+			manifest.Approvals[i] = forged.Approvals[0]
+		}
+	}
+	result, err := approval.Verify(manifest, target, approvers)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Approved)
+	require.EqualError(t, result.Check(), "invalid signatures by bob")
+
+	// A manifest is only valid for its genesis.
+	_, err = approval.Verify(forged, target, approvers)
+	require.Error(t, err)
+	require.Error(t, forged.Sign(target, signers["alice"], "alice"))
+}
+
+func TestLoadApprovers(t *testing.T) {
+	signers := map[string]signature.Signer{
+		"alice": memorySigner.NewTestSigner("alice"),
+		"bob":   memorySigner.NewTestSigner("bob"),
+	}
+	for _, threshold := range []int{0, 3} {
+		_, err := approval.LoadApprovers(writeApprovers(t, threshold, signers))
+		require.Error(t, err, threshold)
+	}
+
+	signers["carol"] = signers["alice"]
+	_, err := approval.LoadApprovers(writeApprovers(t, 1, signers))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is already used by approver")
+
+	path := filepath.Join(tempDir(t), "approvers.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("threshold: 1\napprovers:\n  alice: nope\n"), 0o644))
+	_, err = approval.LoadApprovers(path)
+	require.Error(t, err)
+}
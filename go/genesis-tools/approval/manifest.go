@@ -0,0 +1,133 @@
+// Package approval records and verifies signed approvals of a genesis.
+package approval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// SignatureContext is the context of approval signatures, so that they can
+// not be mistaken for any other signature of the keys.
+var SignatureContext = signature.NewContext("mainnet-entities: genesis approval")
+
+// Kinds of genesis files that can be approved.
+const (
+	// KindStaking is a staking genesis, as written by staking_genesis.
+	KindStaking = "staking"
+	// KindGenesis is a full genesis document.
+	KindGenesis = "genesis"
+)
+
+// Target is the genesis that is approved, identified by the hash of its
+// canonical cbor encoding. The hash of a full genesis document is the one
+// its chain context is derived from.
+type Target struct {
+	Kind string    `json:"kind"`
+	Hash hash.Hash `json:"hash"`
+}
+
+// LoadTarget loads a staking genesis or a full genesis document and hashes
+// it.
+func LoadTarget(path string) (*Target, error) {
+	stakingGenesis, doc, err := stakinggenesis.LoadStakingGenesis(path)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return &Target{Kind: KindStaking, Hash: hash.NewFrom(stakingGenesis)}, nil
+	}
+	return &Target{Kind: KindGenesis, Hash: doc.Hash()}, nil
+}
+
+// String returns the kind and the hash, in base64 like in manifests.
+func (t *Target) String() string {
+	b, _ := t.Hash.MarshalText()
+	return fmt.Sprintf("%s genesis %s", t.Kind, b)
+}
+
+// message is what approvers sign, it binds the kind along with the hash.
+func (t *Target) message() []byte {
+	return cbor.Marshal(t)
+}
+
+// Approval is the signature of an approver.
+type Approval struct {
+	// Name is the name the approver signed with, it is informational and
+	// not covered by the signature.
+	Name string `json:"name,omitempty"`
+	signature.Signature
+}
+
+// Manifest collects the approvals of a genesis.
+type Manifest struct {
+	Target
+	// Approvals are ordered by public key.
+	Approvals []*Approval `json:"approvals"`
+}
+
+// NewManifest returns a manifest without approvals.
+func NewManifest(target *Target) *Manifest {
+	return &Manifest{Target: *target}
+}
+
+// LoadManifest loads a manifest written by Manifest.Write.
+func LoadManifest(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err = json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Write writes the manifest as json.
+func (m *Manifest) Write(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// checkTarget returns an error if the manifest is for another genesis.
+func (m *Manifest) checkTarget(target *Target) error {
+	if m.Kind != target.Kind || !m.Hash.Equal(&target.Hash) {
+		return fmt.Errorf("the manifest approves %s, not %s", &m.Target, target)
+	}
+	return nil
+}
+
+// Sign adds the approval of the signer to the manifest, replacing a previous
+// approval by the same key.
+func (m *Manifest) Sign(target *Target, signer signature.Signer, name string) error {
+	if err := m.checkTarget(target); err != nil {
+		return err
+	}
+	sig, err := signature.Sign(signer, SignatureContext, target.message())
+	if err != nil {
+		return err
+	}
+
+	approvals := []*Approval{{Name: name, Signature: *sig}}
+	for _, approval := range m.Approvals {
+		if !approval.PublicKey.Equal(sig.PublicKey) {
+			approvals = append(approvals, approval)
+		}
+	}
+	sort.Slice(approvals, func(i, j int) bool {
+		return bytes.Compare(approvals[i].PublicKey[:], approvals[j].PublicKey[:]) < 0
+	})
+	m.Approvals = approvals
+	return nil
+}
@@ -0,0 +1,175 @@
+package approval
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// Statuses of an approver in a Result.
+const (
+	StatusApproved = "approved"
+	StatusMissing  = "missing"
+	StatusInvalid  = "invalid signature"
+)
+
+// Approvers is the set of keys whose approvals count, and how many are
+// needed.
+type Approvers struct {
+	// Threshold is the number of approvers that must sign.
+	Threshold int
+	// Keys maps the names of the approvers to their public keys.
+	Keys map[string]signature.PublicKey
+}
+
+// LoadApprovers loads a yaml file of the threshold and the base64 public
+// keys of the approvers by name:
+//
+//	threshold: 2
+//	approvers:
+//	  alice: "..."
+func LoadApprovers(path string) (*Approvers, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Threshold int               `yaml:"threshold"`
+		Approvers map[string]string `yaml:"approvers"`
+	}
+	if err = yaml.UnmarshalStrict(b, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	approvers := &Approvers{
+		Threshold: raw.Threshold,
+		Keys:      make(map[string]signature.PublicKey),
+	}
+	names := make(map[signature.PublicKey]string)
+	for name, rawKey := range raw.Approvers {
+		var pk signature.PublicKey
+		if err = pk.UnmarshalText([]byte(rawKey)); err != nil {
+			return nil, fmt.Errorf("%s: approver %s: malformed public key %q: %w", path, name, rawKey, err)
+		}
+		if other, ok := names[pk]; ok {
+			return nil, fmt.Errorf("%s: approver %s: public key %s is already used by approver %s", path, name, pk, other)
+		}
+		names[pk] = name
+		approvers.Keys[name] = pk
+	}
+	if approvers.Threshold < 1 || approvers.Threshold > len(approvers.Keys) {
+		return nil, fmt.Errorf("%s: threshold %d must be between 1 and the %d approver(s)", path, approvers.Threshold, len(approvers.Keys))
+	}
+	return approvers, nil
+}
+
+// ApproverStatus is whether an approver signed.
+type ApproverStatus struct {
+	Name      string
+	PublicKey signature.PublicKey
+	Status    string
+}
+
+// Result is the verification of a manifest against the approvers.
+type Result struct {
+	Target    Target
+	Threshold int
+	// Approved is the number of approvers with a valid signature.
+	Approved int
+	// Approvers are ordered by name.
+	Approvers []*ApproverStatus
+	// Unknown are the signers of the manifest that are not approvers, they
+	// do not count towards the threshold.
+	Unknown []*Approval
+}
+
+// Verify checks the signatures of the manifest against the approvers. It
+// returns an error if the manifest is for another genesis, Result.Check
+// tells if the genesis is approved.
+func Verify(manifest *Manifest, target *Target, approvers *Approvers) (*Result, error) {
+	if err := manifest.checkTarget(target); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Target:    *target,
+		Threshold: approvers.Threshold,
+	}
+	byKey := make(map[signature.PublicKey]*ApproverStatus)
+	for name, pk := range approvers.Keys {
+		status := &ApproverStatus{Name: name, PublicKey: pk, Status: StatusMissing}
+		byKey[pk] = status
+		result.Approvers = append(result.Approvers, status)
+	}
+	sort.Slice(result.Approvers, func(i, j int) bool {
+		return result.Approvers[i].Name < result.Approvers[j].Name
+	})
+
+	message := target.message()
+	for _, approval := range manifest.Approvals {
+		status, ok := byKey[approval.PublicKey]
+		if !ok {
+			result.Unknown = append(result.Unknown, approval)
+			continue
+		}
+		// An approver is counted once however many times it signed, a
+		// valid signature wins over an invalid one.
+		if status.Status == StatusApproved {
+			continue
+		}
+		if approval.Verify(SignatureContext, message) {
+			status.Status = StatusApproved
+			result.Approved++
+		} else {
+			status.Status = StatusInvalid
+		}
+	}
+	sort.Slice(result.Unknown, func(i, j int) bool {
+		return bytes.Compare(result.Unknown[i].PublicKey[:], result.Unknown[j].PublicKey[:]) < 0
+	})
+	return result, nil
+}
+
+// Check returns an error if an approver signed with an invalid signature or
+// fewer approvers than the threshold signed.
+func (r *Result) Check() error {
+	var invalid []string
+	for _, approver := range r.Approvers {
+		if approver.Status == StatusInvalid {
+			invalid = append(invalid, approver.Name)
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid signatures by %s", strings.Join(invalid, ", "))
+	}
+	if r.Approved < r.Threshold {
+		return fmt.Errorf("%d of %d required approvals", r.Approved, r.Threshold)
+	}
+	return nil
+}
+
+// WriteResult writes the status of every approver as a table.
+func WriteResult(w io.Writer, r *Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s: %d of %d required approvals\n", &r.Target, r.Approved, r.Threshold)
+	fmt.Fprintln(tw, "approver\tpublic key\tstatus")
+	for _, approver := range r.Approvers {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", approver.Name, approver.PublicKey, approver.Status)
+	}
+	for _, approval := range r.Unknown {
+		name := approval.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\tnot an approver\n", name, approval.PublicKey)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,209 @@
+package assembler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	epochtime "github.com/oasisprotocol/oasis-core/go/epochtime/api"
+	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+)
+
+// ChainContextField is the pseudo field used to record the chain context of
+// the variants in the differences.
+const ChainContextField = "(chain context)"
+
+// Difference is a field that differs between the final and the test-time
+// genesis documents.
+type Difference struct {
+	// Field is the JSON path of the field.
+	Field    string `json:"field"`
+	Final    string `json:"final"`
+	TestTime string `json:"test_time"`
+}
+
+// Result is the outcome of assembling a genesis document.
+type Result struct {
+	Final    *genesis.Document
+	TestTime *genesis.Document
+	// FinalChainContext and TestTimeChainContext are the chain contexts, the
+	// hashes of the documents, that every signature on the network is bound
+	// to.
+	FinalChainContext    string
+	TestTimeChainContext string
+	// Differences are the fields that differ between the two documents.
+	Differences []Difference
+}
+
+// ChainID returns the chain ID for a genesis time, the prefix followed by the
+// UTC date and unix timestamp of the genesis time.
+func ChainID(prefix string, genesisTime time.Time) string {
+	genesisTime = genesisTime.UTC()
+	return fmt.Sprintf("%s-%s-%d", prefix, genesisTime.Format("2006-01-02"), genesisTime.Unix())
+}
+
+// Assemble builds the final and test-time genesis documents. Both are built
+// from the same in-memory document and only differ in their genesis time, so
+// the test-time document exercises exactly what will be launched. The
+// consensus parameters of every section come from the network params and the
+// document must pass the sanity checks.
+func Assemble(options Options) (*Result, error) {
+	if options.ChainIDPrefix == "" {
+		return nil, fmt.Errorf("chain ID prefix is not set")
+	}
+	if options.GenesisTime.IsZero() {
+		return nil, fmt.Errorf("genesis time is not set")
+	}
+	if options.TestTime.IsZero() {
+		return nil, fmt.Errorf("test time is not set")
+	}
+	if options.Staking == nil || options.Registry == nil {
+		return nil, fmt.Errorf("staking and registry genesis are required")
+	}
+
+	params := options.Parameters
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	stakingGenesis := *options.Staking
+	stakingGenesis.Parameters = params.Staking
+	registryGenesis := *options.Registry
+	registryGenesis.Parameters = params.Registry
+	final := &genesis.Document{
+		Height:  1,
+		ChainID: ChainID(options.ChainIDPrefix, options.GenesisTime),
+		Time:    options.GenesisTime.UTC(),
+		EpochTime: epochtime.Genesis{
+			Parameters: params.EpochTime,
+		},
+		Registry:  registryGenesis,
+		RootHash:  roothash.Genesis{Parameters: params.RootHash},
+		Staking:   stakingGenesis,
+		Scheduler: scheduler.Genesis{Parameters: params.Scheduler},
+		Beacon:    beacon.Genesis{Parameters: params.Beacon},
+		Consensus: params.Consensus,
+		HaltEpoch: options.HaltEpoch,
+	}
+	if err := genesischeck.Check(final); err != nil {
+		return nil, err
+	}
+
+	testTime := *final
+	testTime.Time = options.TestTime.UTC()
+
+	result := &Result{
+		Final:                final,
+		TestTime:             &testTime,
+		FinalChainContext:    final.ChainContext(),
+		TestTimeChainContext: testTime.ChainContext(),
+	}
+
+	differences, err := diffDocuments(final, &testTime)
+	if err != nil {
+		return nil, err
+	}
+	if result.FinalChainContext != result.TestTimeChainContext {
+		differences = append(differences, Difference{
+			Field:    ChainContextField,
+			Final:    result.FinalChainContext,
+			TestTime: result.TestTimeChainContext,
+		})
+	}
+	result.Differences = differences
+
+	return result, nil
+}
+
+// diffDocuments returns the JSON fields that differ between two documents.
+func diffDocuments(a, b *genesis.Document) ([]Difference, error) {
+	aValue, err := toJSONValue(a)
+	if err != nil {
+		return nil, err
+	}
+	bValue, err := toJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var differences []Difference
+	diffValues("", aValue, bValue, &differences)
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Field < differences[j].Field
+	})
+	return differences, nil
+}
+
+func diffValues(path string, a, b interface{}, differences *[]Difference) {
+	aMap, aIsMap := a.(map[string]interface{})
+	bMap, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := make(map[string]bool)
+		for k := range aMap {
+			keys[k] = true
+		}
+		for k := range bMap {
+			keys[k] = true
+		}
+		for k := range keys {
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+			diffValues(field, aMap[k], bMap[k], differences)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*differences = append(*differences, Difference{
+			Field:    path,
+			Final:    formatValue(a),
+			TestTime: formatValue(b),
+		})
+	}
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// toJSONValue returns the generic JSON representation of v. Numbers are kept
+// as json.Number so that large quantities keep their precision.
+func toJSONValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err = decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// WriteDocument writes a genesis document as indented JSON with sorted keys.
+func WriteDocument(path string, doc *genesis.Document) error {
+	value, err := toJSONValue(doc)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,143 @@
+package assembler_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/assembler"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/networkparams"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	consensusGenesis "github.com/oasisprotocol/oasis-core/go/consensus/genesis"
+	epochtime "github.com/oasisprotocol/oasis-core/go/epochtime/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+var (
+	genesisTime = time.Date(2020, 9, 22, 16, 0, 0, 0, time.UTC)
+	testTime    = time.Date(2020, 9, 1, 12, 30, 0, 0, time.UTC)
+)
+
+func testParams() networkparams.NetworkParams {
+	thresholds := make(map[staking.ThresholdKind]quantity.Quantity)
+	for kind := staking.KindEntity; kind <= staking.KindMax; kind++ {
+		thresholds[kind] = *quantity.NewFromUint64(100)
+	}
+	return networkparams.NetworkParams{
+		Consensus: consensusGenesis.Genesis{
+			Backend: networkparams.ConsensusBackend,
+			Parameters: consensusGenesis.Parameters{
+				TimeoutCommit:  5 * time.Second,
+				MaxTxSize:      32 * 1024,
+				MaxBlockSize:   21 * 1024 * 1024,
+				MaxEvidenceNum: 50,
+			},
+		},
+		EpochTime: epochtime.ConsensusParameters{Interval: 600},
+		Registry:  registry.ConsensusParameters{MaxNodeExpiration: 2},
+		Staking: staking.ConsensusParameters{
+			Thresholds:         thresholds,
+			FeeSplitWeightVote: *quantity.NewFromUint64(1),
+		},
+		Scheduler: scheduler.ConsensusParameters{
+			MinValidators:          1,
+			MaxValidators:          100,
+			MaxValidatorsPerEntity: 1,
+		},
+	}
+}
+
+func testOptions() assembler.Options {
+	return assembler.Options{
+		ChainIDPrefix: "mainnet-test",
+		GenesisTime:   genesisTime,
+		TestTime:      testTime,
+		HaltEpoch:     336,
+		Staking: &staking.Genesis{
+			TokenSymbol: "TEST",
+			TotalSupply: *quantity.NewFromUint64(1000),
+			CommonPool:  *quantity.NewFromUint64(1000),
+		},
+		Registry:   &registry.Genesis{},
+		Parameters: testParams(),
+	}
+}
+
+func TestChainID(t *testing.T) {
+	require.Equal(t, "mainnet-2020-09-22-1600790400", assembler.ChainID("mainnet", genesisTime))
+	require.Equal(t, "mainnet-2020-09-22-1600790400",
+		assembler.ChainID("mainnet", genesisTime.In(time.FixedZone("UTC+9", 9*60*60))))
+}
+
+func TestAssemble(t *testing.T) {
+	result, err := assembler.Assemble(testOptions())
+	require.NoError(t, err)
+
+	require.Equal(t, "mainnet-test-2020-09-22-1600790400", result.Final.ChainID)
+	require.Equal(t, result.Final.ChainID, result.TestTime.ChainID)
+	require.True(t, genesisTime.Equal(result.Final.Time))
+	require.True(t, testTime.Equal(result.TestTime.Time))
+	require.Equal(t, result.Final.ChainContext(), result.FinalChainContext)
+	require.Equal(t, result.TestTime.ChainContext(), result.TestTimeChainContext)
+	require.NotEqual(t, result.FinalChainContext, result.TestTimeChainContext)
+	require.NoError(t, genesischeck.Check(result.TestTime))
+	require.Equal(t, uint64(2), result.Final.Registry.Parameters.MaxNodeExpiration)
+	require.Equal(t, testParams().Staking, result.Final.Staking.Parameters)
+
+	require.Equal(t, []assembler.Difference{
+		{Field: "genesis_time", Final: "2020-09-22T16:00:00Z", TestTime: "2020-09-01T12:30:00Z"},
+		{Field: assembler.ChainContextField, Final: result.FinalChainContext, TestTime: result.TestTimeChainContext},
+	}, result.Differences)
+
+	// Without a separate test time the variants are identical.
+	options := testOptions()
+	options.TestTime = genesisTime
+	result, err = assembler.Assemble(options)
+	require.NoError(t, err)
+	require.Equal(t, result.FinalChainContext, result.TestTimeChainContext)
+	require.Empty(t, result.Differences)
+}
+
+func TestAssembleSanityCheck(t *testing.T) {
+	options := testOptions()
+	options.Parameters.Registry.MaxNodeExpiration = 0
+	options.Parameters.EpochTime.Interval = 0
+
+	_, err := assembler.Assemble(options)
+	var errs genesischeck.Errors
+	require.True(t, errors.As(err, &errs))
+	require.Equal(t, []string{genesischeck.SectionEpochTime, genesischeck.SectionRegistry}, errs.Sections())
+
+	options = testOptions()
+	options.ChainIDPrefix = ""
+	_, err = assembler.Assemble(options)
+	require.Error(t, err)
+}
+
+func TestWriteDocument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assembler")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	options := testOptions()
+	options.Staking.TotalSupply = *quantity.NewFromUint64(12345678901234567890)
+	options.Staking.CommonPool = options.Staking.TotalSupply
+	result, err := assembler.Assemble(options)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "genesis.json")
+	require.NoError(t, assembler.WriteDocument(path, result.Final))
+
+	doc, err := genesischeck.LoadDocument(path)
+	require.NoError(t, err)
+	require.Equal(t, result.FinalChainContext, doc.ChainContext())
+	require.Equal(t, result.Final.Staking.TotalSupply, doc.Staking.TotalSupply)
+}
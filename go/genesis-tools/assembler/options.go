@@ -0,0 +1,29 @@
+package assembler
+
+import (
+	"time"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/networkparams"
+	epochtime "github.com/oasisprotocol/oasis-core/go/epochtime/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// Options options for assembling a genesis document.
+type Options struct {
+	// ChainIDPrefix is the prefix of the chain ID, the genesis time is
+	// appended to it.
+	ChainIDPrefix string
+	// GenesisTime is the genesis time of the final document.
+	GenesisTime time.Time
+	// TestTime is the genesis time of the test-time document, which can be
+	// used to start a node before the genesis time.
+	TestTime  time.Time
+	HaltEpoch epochtime.EpochTime
+
+	Staking  *staking.Genesis
+	Registry *registry.Genesis
+	// Parameters are the consensus parameters of every section, they
+	// replace the parameters of the staking and registry genesis.
+	Parameters networkparams.NetworkParams
+}
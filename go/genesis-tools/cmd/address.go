@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/addressutil"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+)
+
+const (
+	cfgAddressCSVPath         = "address.csv"
+	cfgAddressCSVColumn       = "address.column"
+	cfgAddressFormat          = "address.format"
+	cfgAddressEntitiesDirPath = "address.entities_dir"
+	cfgAddressConfigPath      = "address.config"
+)
+
+var (
+	addressCmd = &cobra.Command{
+		Use:   "address",
+		Short: "Converts between public keys and staking addresses",
+	}
+
+	addressConvertCmd = &cobra.Command{
+		Use:   "convert [key or address...]",
+		Short: "Converts public keys to staking addresses",
+		Long: `Converts public keys to staking addresses
+
+        Keys are base64 or hex, addresses are only validated. The
+        conversions are written as a table, one address per line (plain)
+        or json. With a csv, the csv is written back with the public
+        key, public key hex, address and error columns appended.`,
+		Run: doAddressConvert,
+	}
+
+	addressResolveCmd = &cobra.Command{
+		Use:   "resolve [entity name...]",
+		Short: "Prints the addresses of entities",
+		Long: `Prints the addresses of entities
+
+        Prints every entity of the entities directories if no names
+        are given.`,
+		Run: doAddressResolve,
+	}
+
+	addressValidateConfigCmd = &cobra.Command{
+		Use:   "validate-config",
+		Short: "Validates the account addresses of a staking configuration",
+		Run:   doAddressValidateConfig,
+	}
+
+	addressConvertFlags  = flag.NewFlagSet("", flag.ContinueOnError)
+	addressEntitiesFlags = flag.NewFlagSet("", flag.ContinueOnError)
+	addressConfigFlags   = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doAddressConvert(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	if csvPath := viper.GetString(cfgAddressCSVPath); csvPath != "" {
+		f, err := os.Open(csvPath)
+		if err != nil {
+			logger.Error("failed to open the csv",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		failed, err := addressutil.ConvertCSV(f, os.Stdout, viper.GetString(cfgAddressCSVColumn))
+		if err != nil {
+			logger.Error("failed to convert the csv",
+				"path", csvPath,
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		if failed > 0 {
+			logger.Error("some values could not be converted",
+				"failed", failed,
+			)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) == 0 {
+		logger.Error("must give keys or addresses, or a csv")
+		os.Exit(1)
+	}
+	var conversions []*addressutil.Conversion
+	for _, arg := range args {
+		c, err := addressutil.Convert(arg)
+		if err != nil {
+			logger.Error("failed to convert",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		conversions = append(conversions, c)
+	}
+	if err := addressutil.WriteConversions(os.Stdout, conversions, viper.GetString(cfgAddressFormat)); err != nil {
+		logger.Error("failed to write the conversions",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+func doAddressResolve(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	entitiesDir := loadAddressEntitiesDir()
+	if entitiesDir == nil {
+		logger.Error("must define an entities directory path")
+		os.Exit(1)
+	}
+	addresses, err := addressutil.ResolveEntities(entitiesDir, args)
+	if err != nil {
+		logger.Error("failed to resolve the entities",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if err = addressutil.WriteNamedAddresses(os.Stdout, addresses); err != nil {
+		logger.Error("failed to write the addresses",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+func doAddressValidateConfig(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	configPath := viper.GetString(cfgAddressConfigPath)
+	if configPath == "" {
+		logger.Error("must set the staking configuration path")
+		os.Exit(1)
+	}
+	config, err := stakinggenesis.LoadGenesisConfig(configPath)
+	if err != nil {
+		logger.Error("invalid staking configuration",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	var entities stakinggenesis.Entities
+	if entitiesDir := loadAddressEntitiesDir(); entitiesDir != nil {
+		entities = entitiesDir
+	}
+	addresses, err := addressutil.CheckConfigAddresses(config, entities)
+	if writeErr := addressutil.WriteNamedAddresses(os.Stdout, addresses); writeErr != nil {
+		logger.Error("failed to write the addresses",
+			"err", writeErr,
+		)
+		os.Exit(1)
+	}
+	if err != nil {
+		logger.Error("invalid account addresses",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+// loadAddressEntitiesDir loads the entities directories, it returns nil if
+// none are configured.
+func loadAddressEntitiesDir() *stakinggenesis.EntitiesDirectory {
+	entitiesDirPaths := viper.GetStringSlice(cfgAddressEntitiesDirPath)
+	if len(entitiesDirPaths) == 0 {
+		return nil
+	}
+	entitiesDir, err := stakinggenesis.LoadEntitiesDirectory(entitiesDirPaths)
+	if err != nil {
+		logLoadErrors(err)
+		logger.Error("Cannot load entities")
+		os.Exit(1)
+	}
+	return entitiesDir
+}
+
+// RegisterAddressCmd registers the address subcommands.
+func RegisterAddressCmd(parentCmd *cobra.Command) {
+	addressConvertFlags.String(cfgAddressCSVPath, "", "a csv file of keys or addresses to convert")
+	addressConvertFlags.String(cfgAddressCSVColumn, "public_key", "the csv column of the keys or addresses")
+	addressConvertFlags.String(cfgAddressFormat, addressutil.FormatTable,
+		"output format of the conversions: table, plain (one address per line) or json")
+	addressEntitiesFlags.StringSlice(cfgAddressEntitiesDirPath, []string{}, "directories of entity packages")
+	addressConfigFlags.String(cfgAddressConfigPath, "", "a staking configuration yaml file")
+	_ = viper.BindPFlags(addressConvertFlags)
+	_ = viper.BindPFlags(addressEntitiesFlags)
+	_ = viper.BindPFlags(addressConfigFlags)
+
+	addressConvertCmd.Flags().AddFlagSet(addressConvertFlags)
+	addressResolveCmd.Flags().AddFlagSet(addressEntitiesFlags)
+	addressValidateConfigCmd.Flags().AddFlagSet(addressConfigFlags)
+	addressValidateConfigCmd.Flags().AddFlagSet(addressEntitiesFlags)
+
+	addressCmd.AddCommand(addressConvertCmd)
+	addressCmd.AddCommand(addressResolveCmd)
+	addressCmd.AddCommand(addressValidateConfigCmd)
+	parentCmd.AddCommand(addressCmd)
+}
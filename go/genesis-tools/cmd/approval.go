@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/approval"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	fileSigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/file"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+)
+
+const (
+	cfgSignGenesisPath     = "sign.genesis"
+	cfgSignSignerDir       = "sign.signer_dir"
+	cfgSignManifestPath    = "sign.manifest"
+	cfgSignName            = "sign.name"
+	cfgVerifyGenesisPath   = "verify.genesis"
+	cfgVerifyManifestPath  = "verify.manifest"
+	cfgVerifyApproversPath = "verify.approvers"
+)
+
+var (
+	signCmd = &cobra.Command{
+		Use:   "sign",
+		Short: "Approves a staking genesis or genesis document",
+		Long: `Approves a staking genesis or genesis document
+
+        Signs the hash of the canonical encoding of the genesis with the
+        entity key of the signer directory and adds the signature to
+        the approval manifest, creating it if needed.`,
+		Run: doSign,
+	}
+
+	verifyApprovalsCmd = &cobra.Command{
+		Use:   "verify-approvals",
+		Short: "Verifies the approvals of a staking genesis or genesis document",
+		Long: `Verifies the approvals of a staking genesis or genesis document
+
+        Fails unless at least threshold approvers of the approvers file
+        signed the genesis and none of them signed with an invalid
+        signature.`,
+		Run: doVerifyApprovals,
+	}
+
+	signFlags            = flag.NewFlagSet("", flag.ContinueOnError)
+	verifyApprovalsFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doSign(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	genesisPath := viper.GetString(cfgSignGenesisPath)
+	signerDir := viper.GetString(cfgSignSignerDir)
+	manifestPath := viper.GetString(cfgSignManifestPath)
+	if genesisPath == "" || signerDir == "" || manifestPath == "" {
+		logger.Error("must set the genesis, signer directory and manifest paths")
+		os.Exit(1)
+	}
+
+	target, err := approval.LoadTarget(genesisPath)
+	if err != nil {
+		logger.Error("failed to load the genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	signerFactory, err := fileSigner.NewFactory(signerDir, signature.SignerEntity)
+	if err != nil {
+		logger.Error("failed to create the signer factory",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	signer, err := signerFactory.Load(signature.SignerEntity)
+	if err != nil {
+		logger.Error("failed to load the signer",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	defer signer.Reset()
+
+	manifest, err := approval.LoadManifest(manifestPath)
+	switch {
+	case os.IsNotExist(err):
+		manifest = approval.NewManifest(target)
+	case err != nil:
+		logger.Error("failed to load the manifest",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if err = manifest.Sign(target, signer, viper.GetString(cfgSignName)); err != nil {
+		logger.Error("failed to sign the genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if err = manifest.Write(manifestPath); err != nil {
+		logger.Error("failed to write the manifest",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	logger.Info("approved the genesis",
+		"kind", target.Kind,
+		"hash", target.Hash,
+		"public_key", signer.Public(),
+		"approvals", len(manifest.Approvals),
+	)
+}
+
+func doVerifyApprovals(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	genesisPath := viper.GetString(cfgVerifyGenesisPath)
+	manifestPath := viper.GetString(cfgVerifyManifestPath)
+	approversPath := viper.GetString(cfgVerifyApproversPath)
+	if genesisPath == "" || manifestPath == "" || approversPath == "" {
+		logger.Error("must set the genesis, manifest and approvers paths")
+		os.Exit(1)
+	}
+
+	target, err := approval.LoadTarget(genesisPath)
+	if err != nil {
+		logger.Error("failed to load the genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	manifest, err := approval.LoadManifest(manifestPath)
+	if err != nil {
+		logger.Error("failed to load the manifest",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	approvers, err := approval.LoadApprovers(approversPath)
+	if err != nil {
+		logger.Error("failed to load the approvers",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	result, err := approval.Verify(manifest, target, approvers)
+	if err != nil {
+		logger.Error("failed to verify the approvals",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if err = approval.WriteResult(os.Stdout, result); err != nil {
+		logger.Error("failed to write the approvals",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if err = result.Check(); err != nil {
+		logger.Error("the genesis is not approved",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+// RegisterApprovalCmds registers the sign and verify-approvals subcommands.
+func RegisterApprovalCmds(parentCmd *cobra.Command) {
+	signFlags.String(cfgSignGenesisPath, "", "a staking genesis or genesis document json file")
+	signFlags.String(cfgSignSignerDir, "", "a directory with the entity.pem key to sign with")
+	signFlags.String(cfgSignManifestPath, "", "the approval manifest json file, created if missing")
+	signFlags.String(cfgSignName, "", "a name recorded with the approval")
+	_ = viper.BindPFlags(signFlags)
+
+	verifyApprovalsFlags.String(cfgVerifyGenesisPath, "", "a staking genesis or genesis document json file")
+	verifyApprovalsFlags.String(cfgVerifyManifestPath, "", "the approval manifest json file")
+	verifyApprovalsFlags.String(cfgVerifyApproversPath, "",
+		"a yaml file of the approval threshold and the public keys of the approvers")
+	_ = viper.BindPFlags(verifyApprovalsFlags)
+
+	signCmd.Flags().AddFlagSet(signFlags)
+	verifyApprovalsCmd.Flags().AddFlagSet(verifyApprovalsFlags)
+
+	parentCmd.AddCommand(signCmd)
+	parentCmd.AddCommand(verifyApprovalsCmd)
+}
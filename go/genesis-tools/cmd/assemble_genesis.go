@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/assembler"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/registrygenesis"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	epochtime "github.com/oasisprotocol/oasis-core/go/epochtime/api"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+const (
+	cfgAssembleEntitiesDirPaths       = "assemble.entities_dir"
+	cfgAssembleEntityOnlyDirPaths     = "assemble.entity_only_dir"
+	cfgAssembleStakingPath            = "assemble.staking"
+	cfgAssembleNetworkParamsPath      = "assemble.network_params"
+	cfgAssembleNodePolicyPath         = "assemble.node_policy"
+	cfgAssembleChainIDPrefix          = "assemble.chain_id_prefix"
+	cfgAssembleGenesisTime            = "assemble.genesis_time"
+	cfgAssembleTestTime               = "assemble.test_time"
+	cfgAssembleHaltEpoch              = "assemble.halt_epoch"
+	cfgAssembleMaxValidators          = "assemble.max_validators"
+	cfgAssembleMinValidators          = "assemble.min_validators"
+	cfgAssembleMaxValidatorsPerEntity = "assemble.max_validators_per_entity"
+	cfgAssembleOutputPath             = "assemble.output_path"
+	cfgAssembleTestTimeOutputPath     = "assemble.test_time_output_path"
+	cfgAssembleDifferencesPath        = "assemble.differences_path"
+
+	// genesisTimeFormat is the format of the genesis time flags, in UTC.
+	genesisTimeFormat = "2006-01-02T15:04:05"
+)
+
+var (
+	assembleGenesisCmd = &cobra.Command{
+		Use:   "assemble-genesis",
+		Short: "Assembles a genesis document",
+		Long: `Assembles a genesis document
+
+        Builds the registry from directories of unpacked Entity Packages
+        and combines it with a staking genesis and the network params.
+        The scheduler flags override the network params when set.
+        A final document with the genesis time and a test-time document
+        that can be launched immediately are written from the same
+        document, the fields that differ between them are reported.`,
+		Run: doAssembleGenesis,
+	}
+
+	assembleGenesisFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func parseGenesisTime(key string) (time.Time, error) {
+	value := viper.GetString(key)
+	if value == "" {
+		return time.Now().UTC().Truncate(time.Second), nil
+	}
+	return time.ParseInLocation(genesisTimeFormat, value, time.UTC)
+}
+
+func doAssembleGenesis(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	outputPath := viper.GetString(cfgAssembleOutputPath)
+	if outputPath == "" {
+		logger.Error("must set output path for the genesis document")
+		os.Exit(1)
+	}
+
+	options := assembler.Options{
+		ChainIDPrefix: viper.GetString(cfgAssembleChainIDPrefix),
+		HaltEpoch:     epochtime.EpochTime(viper.GetUint64(cfgAssembleHaltEpoch)),
+	}
+	params, err := loadNetworkParams(viper.GetString(cfgAssembleNetworkParamsPath))
+	if err != nil {
+		logger.Error("failed to load the network params",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	options.Parameters = *params
+	if options.GenesisTime, err = parseGenesisTime(cfgAssembleGenesisTime); err != nil {
+		logger.Error("invalid genesis time",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if options.TestTime, err = parseGenesisTime(cfgAssembleTestTime); err != nil {
+		logger.Error("invalid test time",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	if cmd.Flags().Changed(cfgAssembleMaxValidators) {
+		options.Parameters.Scheduler.MaxValidators = viper.GetInt(cfgAssembleMaxValidators)
+	}
+	if cmd.Flags().Changed(cfgAssembleMinValidators) {
+		options.Parameters.Scheduler.MinValidators = viper.GetInt(cfgAssembleMinValidators)
+	}
+	if cmd.Flags().Changed(cfgAssembleMaxValidatorsPerEntity) {
+		options.Parameters.Scheduler.MaxValidatorsPerEntity = viper.GetInt(cfgAssembleMaxValidatorsPerEntity)
+	}
+
+	if options.Staking, _, err = stakinggenesis.LoadStakingGenesis(viper.GetString(cfgAssembleStakingPath)); err != nil {
+		logger.Error("failed to load the staking genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	packages, err := loadRegistryPackages(viper.GetStringSlice(cfgAssembleEntitiesDirPaths))
+	if err != nil {
+		logLoadErrors(err)
+		logger.Error("Cannot load entities")
+		os.Exit(1)
+	}
+	entityOnlyPackages, err := loadRegistryPackages(viper.GetStringSlice(cfgAssembleEntityOnlyDirPaths))
+	if err != nil {
+		logLoadErrors(err)
+		logger.Error("Cannot load entity only entities")
+		os.Exit(1)
+	}
+	registryOptions := registrygenesis.GenesisOptions{
+		Packages:           packages,
+		EntityOnlyPackages: entityOnlyPackages,
+		ConsensusParametersLoader: func() registry.ConsensusParameters {
+			return params.Registry
+		},
+	}
+	if nodePolicyPath := viper.GetString(cfgAssembleNodePolicyPath); nodePolicyPath != "" {
+		if registryOptions.NodePolicy, err = stakinggenesis.LoadNodePolicy(nodePolicyPath); err != nil {
+			logger.Error("failed to load the node policy",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
+	if options.Registry, err = registrygenesis.Create(registryOptions); err != nil {
+		logKeyConflicts(err)
+		logger.Error("failed to create the registry genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	result, err := assembler.Assemble(options)
+	var sectionErrs genesischeck.Errors
+	switch {
+	case errors.As(err, &sectionErrs):
+		for _, sectionErr := range sectionErrs {
+			logger.Error("genesis section failed the sanity check",
+				"section", sectionErr.Section,
+				"err", sectionErr.Err,
+			)
+		}
+		os.Exit(1)
+	case err != nil:
+		logger.Error("failed to assemble the genesis document",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	if err = assembler.WriteDocument(outputPath, result.Final); err != nil {
+		logger.Error("failed to write the genesis document",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if testTimeOutputPath := viper.GetString(cfgAssembleTestTimeOutputPath); testTimeOutputPath != "" {
+		if err = assembler.WriteDocument(testTimeOutputPath, result.TestTime); err != nil {
+			logger.Error("failed to write the test-time genesis document",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
+	if differencesPath := viper.GetString(cfgAssembleDifferencesPath); differencesPath != "" {
+		b, err := json.MarshalIndent(result.Differences, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(differencesPath, b, 0o644)
+		}
+		if err != nil {
+			logger.Error("failed to write the differences",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("chain id: %s\n", result.Final.ChainID)
+	fmt.Printf("genesis time: %s\n", result.Final.Time.Format(time.RFC3339))
+	fmt.Printf("chain context: %s\n", result.FinalChainContext)
+	fmt.Printf("test-time chain context: %s\n", result.TestTimeChainContext)
+	fmt.Printf("%d field(s) differ in the test-time document:\n", len(result.Differences))
+	for _, difference := range result.Differences {
+		fmt.Printf("  - %s: %s (test-time %s)\n", difference.Field, difference.Final, difference.TestTime)
+	}
+}
+
+// RegisterAssembleGenesisCmd registers the assemble-genesis subcommand.
+func RegisterAssembleGenesisCmd(parentCmd *cobra.Command) {
+	assembleGenesisFlags.StringSlice(cfgAssembleEntitiesDirPaths, []string{},
+		"directories of entity packages registered with their nodes")
+	assembleGenesisFlags.StringSlice(cfgAssembleEntityOnlyDirPaths, []string{},
+		"directories of entity packages registered without their nodes")
+	assembleGenesisFlags.String(cfgAssembleStakingPath, "", "a staking genesis json file")
+	assembleGenesisFlags.String(cfgAssembleNetworkParamsPath, "", "a network params json file")
+	assembleGenesisFlags.String(cfgAssembleNodePolicyPath, "",
+		"a yaml node policy, nodes violating it are not registered")
+	assembleGenesisFlags.String(cfgAssembleChainIDPrefix, "mainnet", "the chain ID prefix")
+	assembleGenesisFlags.String(cfgAssembleGenesisTime, "",
+		"genesis time in UTC as "+genesisTimeFormat+" (defaults to now)")
+	assembleGenesisFlags.String(cfgAssembleTestTime, "",
+		"genesis time of the test-time document in UTC as "+genesisTimeFormat+" (defaults to now)")
+	assembleGenesisFlags.Uint64(cfgAssembleHaltEpoch, 0, "genesis halt epoch")
+	assembleGenesisFlags.Int(cfgAssembleMaxValidators, 0, "override the maximum number of validators")
+	assembleGenesisFlags.Int(cfgAssembleMinValidators, 0, "override the minimum number of validators")
+	assembleGenesisFlags.Int(cfgAssembleMaxValidatorsPerEntity, 0, "override the maximum number of validators per entity")
+	assembleGenesisFlags.String(cfgAssembleOutputPath, "", "output path for the genesis document")
+	assembleGenesisFlags.String(cfgAssembleTestTimeOutputPath, "",
+		"output path for the test-time genesis document")
+	assembleGenesisFlags.String(cfgAssembleDifferencesPath, "",
+		"output path for a json list of the fields that differ between the documents")
+	_ = viper.BindPFlags(assembleGenesisFlags)
+
+	assembleGenesisCmd.Flags().AddFlagSet(assembleGenesisFlags)
+
+	parentCmd.AddCommand(assembleGenesisCmd)
+}
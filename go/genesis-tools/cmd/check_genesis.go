@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+)
+
+const (
+	cfgCheckGenesisPath = "check.genesis"
+)
+
+var (
+	checkGenesisCmd = &cobra.Command{
+		Use:   "check-genesis",
+		Short: "Sanity checks a genesis document",
+		Long: `Sanity checks a genesis document
+
+        Runs the oasis-core sanity checks on every section of the
+        genesis document without starting a node and reports every
+        section that fails.`,
+		Run: doCheckGenesis,
+	}
+
+	checkGenesisFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doCheckGenesis(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	genesisPath := viper.GetString(cfgCheckGenesisPath)
+	if genesisPath == "" {
+		logger.Error("must set the genesis document path")
+		os.Exit(1)
+	}
+
+	doc, err := genesischeck.LoadDocument(genesisPath)
+	if err != nil {
+		logger.Error("failed to load the genesis document",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	err = genesischeck.Check(doc)
+	var sectionErrs genesischeck.Errors
+	switch {
+	case errors.As(err, &sectionErrs):
+		fmt.Printf("found %d problem(s) with the genesis document:\n", len(sectionErrs))
+		for _, sectionErr := range sectionErrs {
+			fmt.Printf("  - %s\n", sectionErr)
+		}
+		os.Exit(1)
+	case err != nil:
+		logger.Error("failed to check the genesis document",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Printf("genesis document %s is valid\n", genesisPath)
+}
+
+// RegisterCheckGenesisCmd registers the check-genesis subcommand.
+func RegisterCheckGenesisCmd(parentCmd *cobra.Command) {
+	checkGenesisFlags.String(cfgCheckGenesisPath, "", "a genesis document json file")
+	_ = viper.BindPFlags(checkGenesisFlags)
+
+	checkGenesisCmd.Flags().AddFlagSet(checkGenesisFlags)
+
+	parentCmd.AddCommand(checkGenesisCmd)
+}
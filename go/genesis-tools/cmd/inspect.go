@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/inspect"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+const (
+	cfgInspectStakingPath     = "inspect.staking"
+	cfgInspectConfigPath      = "inspect.config"
+	cfgInspectEntitiesDirPath = "inspect.entities_dir"
+	cfgInspectAddresses       = "inspect.address"
+	cfgInspectNames           = "inspect.name"
+	cfgInspectMinStake        = "inspect.min_stake"
+	cfgInspectSortBy          = "inspect.sort"
+)
+
+var (
+	inspectCmd = &cobra.Command{
+		Use:   "inspect",
+		Short: "Prints the accounts of a staking genesis",
+		Long: `Prints the accounts of a staking genesis
+
+        Accounts are grouped into the foundation accounts of the staking
+        configuration, the entities of the entities directories and
+        unknown accounts. Amounts are printed in tokens and the minimum
+        stake is configured in whole tokens.`,
+		Run: doInspect,
+	}
+
+	inspectFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doInspect(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	stakingPath := viper.GetString(cfgInspectStakingPath)
+	if stakingPath == "" {
+		logger.Error("must set the staking genesis path")
+		os.Exit(1)
+	}
+	stakingGenesis, _, err := stakinggenesis.LoadStakingGenesis(stakingPath)
+	if err != nil {
+		logger.Error("failed to load the staking genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	options := inspect.Options{
+		Foundation: make(map[staking.Address]string),
+		Entities:   make(map[staking.Address]string),
+		Names:      viper.GetStringSlice(cfgInspectNames),
+		SortBy:     viper.GetString(cfgInspectSortBy),
+	}
+	if configPath := viper.GetString(cfgInspectConfigPath); configPath != "" {
+		config, err := stakinggenesis.LoadGenesisConfig(configPath)
+		if err != nil {
+			logger.Error("failed to load the staking configuration",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		for name, account := range config.Accounts {
+			options.Foundation[account.Address()] = name
+		}
+	}
+	if entitiesDirPaths := viper.GetStringSlice(cfgInspectEntitiesDirPath); len(entitiesDirPaths) > 0 {
+		entitiesDir, err := stakinggenesis.LoadEntitiesDirectory(entitiesDirPaths)
+		if err != nil {
+			logLoadErrors(err)
+			logger.Error("Cannot load entities")
+			os.Exit(1)
+		}
+		for _, pkg := range entitiesDir.Packages() {
+			options.Entities[staking.NewAddress(pkg.Entity.ID)] = pkg.Name
+		}
+	}
+	for _, raw := range viper.GetStringSlice(cfgInspectAddresses) {
+		var address staking.Address
+		if err = address.UnmarshalText([]byte(raw)); err != nil {
+			logger.Error("malformed address",
+				"address", raw,
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		options.Addresses = append(options.Addresses, address)
+	}
+	if minStake := viper.GetUint64(cfgInspectMinStake); minStake > 0 {
+		precision, err := stakinggenesis.Precision(stakingGenesis.TokenValueExponent)
+		if err == nil {
+			options.MinStake = precision
+			err = options.MinStake.Mul(quantity.NewFromUint64(minStake))
+		}
+		if err != nil {
+			logger.Error("invalid minimum stake",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
+
+	accounts, err := inspect.Accounts(stakingGenesis, options)
+	if err != nil {
+		logger.Error("failed to inspect the staking genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if err = inspect.WriteReport(os.Stdout, stakingGenesis, accounts); err != nil {
+		logger.Error("failed to write the accounts",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+// RegisterInspectCmd registers the inspect subcommand.
+func RegisterInspectCmd(parentCmd *cobra.Command) {
+	inspectFlags.String(cfgInspectStakingPath, "", "a staking genesis or genesis document json file")
+	inspectFlags.String(cfgInspectConfigPath, "",
+		"a staking configuration yaml file whose accounts are the foundation accounts")
+	inspectFlags.StringSlice(cfgInspectEntitiesDirPath, []string{},
+		"directories of entity packages used to name entities")
+	inspectFlags.StringSlice(cfgInspectAddresses, []string{}, "only print the accounts with these addresses")
+	inspectFlags.StringSlice(cfgInspectNames, []string{},
+		"only print the foundation accounts and entities with these names")
+	inspectFlags.Uint64(cfgInspectMinStake, 0, "only print the accounts with at least this escrow, in whole tokens")
+	inspectFlags.String(cfgInspectSortBy, inspect.SortAddress,
+		"sort order, one of address, name, general, escrow or total")
+	_ = viper.BindPFlags(inspectFlags)
+
+	inspectCmd.Flags().AddFlagSet(inspectFlags)
+
+	parentCmd.AddCommand(inspectCmd)
+}
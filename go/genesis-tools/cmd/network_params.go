@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/networkparams"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+)
+
+const (
+	cfgNetworkParamsPath = "params.file"
+)
+
+var (
+	networkParamsCmd = &cobra.Command{
+		Use:   "network-params",
+		Short: "Validates and dumps the network parameters",
+		Long: `Validates and dumps the network parameters
+
+        Loads a network params file, rejecting unknown fields and
+        validating every consensus section, and prints the effective
+        parameters exactly as they appear in the genesis document.`,
+		Run: doNetworkParams,
+	}
+
+	networkParamsFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+// loadNetworkParams loads a network params file, logging every section that
+// fails the validation.
+func loadNetworkParams(path string) (*networkparams.NetworkParams, error) {
+	params, err := networkparams.Load(path)
+	var sectionErrs genesischeck.Errors
+	if errors.As(err, &sectionErrs) {
+		for _, sectionErr := range sectionErrs {
+			logger.Error("network params section failed the validation",
+				"section", sectionErr.Section,
+				"err", sectionErr.Err,
+			)
+		}
+	}
+	return params, err
+}
+
+func doNetworkParams(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	paramsPath := viper.GetString(cfgNetworkParamsPath)
+	if paramsPath == "" {
+		logger.Error("must set the network params path")
+		os.Exit(1)
+	}
+
+	params, err := loadNetworkParams(paramsPath)
+	if err != nil {
+		logger.Error("failed to load the network params",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	fmt.Printf("effective network params from %s:\n", paramsPath)
+	if err = networkparams.WriteDump(os.Stdout, params); err != nil {
+		logger.Error("failed to dump the network params",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+// RegisterNetworkParamsCmd registers the network-params subcommand.
+func RegisterNetworkParamsCmd(parentCmd *cobra.Command) {
+	networkParamsFlags.String(cfgNetworkParamsPath, "", "a network params json file")
+	_ = viper.BindPFlags(networkParamsFlags)
+
+	networkParamsCmd.Flags().AddFlagSet(networkParamsFlags)
+
+	parentCmd.AddCommand(networkParamsCmd)
+}
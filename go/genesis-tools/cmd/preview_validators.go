@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/validatorset"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+)
+
+const (
+	cfgPreviewStakingPath            = "preview.staking"
+	cfgPreviewRegistryPath           = "preview.registry"
+	cfgPreviewNetworkParamsPath      = "preview.network_params"
+	cfgPreviewMaxValidators          = "preview.max_validators"
+	cfgPreviewMinValidators          = "preview.min_validators"
+	cfgPreviewMaxValidatorsPerEntity = "preview.max_validators_per_entity"
+	cfgPreviewEntitiesDirPaths       = "preview.entities_dir"
+)
+
+var (
+	previewValidatorsCmd = &cobra.Command{
+		Use:   "preview-validators",
+		Short: "Previews the initial validator set",
+		Long: `Previews the initial validator set
+
+        Uses a staking genesis, a registry genesis and the scheduler
+        parameters to elect the initial validator set the way the
+        scheduler would. The max/min validators flags override the
+        scheduler parameters file.`,
+		Run: doPreviewValidators,
+	}
+
+	previewValidatorsFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doPreviewValidators(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	stakingPath := viper.GetString(cfgPreviewStakingPath)
+	registryPath := viper.GetString(cfgPreviewRegistryPath)
+	if stakingPath == "" || registryPath == "" {
+		logger.Error("must set the staking and registry genesis paths")
+		os.Exit(1)
+	}
+
+	var options validatorset.Options
+	var err error
+	if options.Staking, _, err = stakinggenesis.LoadStakingGenesis(stakingPath); err != nil {
+		logger.Error("failed to load the staking genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if options.Registry, err = validatorset.LoadRegistryGenesis(registryPath); err != nil {
+		logger.Error("failed to load the registry genesis",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if paramsPath := viper.GetString(cfgPreviewNetworkParamsPath); paramsPath != "" {
+		params, err := loadNetworkParams(paramsPath)
+		if err != nil {
+			logger.Error("failed to load the network params",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		options.Parameters = params.Scheduler
+	}
+	if v := viper.GetInt(cfgPreviewMaxValidators); v > 0 {
+		options.Parameters.MaxValidators = v
+	}
+	if v := viper.GetInt(cfgPreviewMinValidators); v > 0 {
+		options.Parameters.MinValidators = v
+	}
+	if v := viper.GetInt(cfgPreviewMaxValidatorsPerEntity); v > 0 {
+		options.Parameters.MaxValidatorsPerEntity = v
+	}
+
+	var names map[signature.PublicKey]string
+	if entitiesDirPaths := viper.GetStringSlice(cfgPreviewEntitiesDirPaths); len(entitiesDirPaths) > 0 {
+		entitiesDir, err := stakinggenesis.LoadEntitiesDirectory(entitiesDirPaths)
+		if err != nil {
+			logLoadErrors(err)
+			logger.Error("Cannot load entities")
+			os.Exit(1)
+		}
+		names = make(map[signature.PublicKey]string)
+		for _, pkg := range entitiesDir.Packages() {
+			names[pkg.Entity.ID] = pkg.Name
+		}
+	}
+
+	preview, err := validatorset.Elect(options)
+	if err != nil {
+		logger.Error("failed to elect the validator set",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if err = validatorset.WriteReport(os.Stdout, preview, names); err != nil {
+		logger.Error("failed to write the validator set",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	if err = preview.CheckMinValidators(options.Parameters); err != nil {
+		logger.Error("insufficient validators",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+// RegisterPreviewValidatorsCmd registers the preview-validators subcommand.
+func RegisterPreviewValidatorsCmd(parentCmd *cobra.Command) {
+	previewValidatorsFlags.String(cfgPreviewStakingPath, "", "a staking genesis json file")
+	previewValidatorsFlags.String(cfgPreviewRegistryPath, "", "a registry genesis json file")
+	previewValidatorsFlags.String(cfgPreviewNetworkParamsPath, "", "a network params json file")
+	previewValidatorsFlags.Int(cfgPreviewMaxValidators, 0, "maximum number of validators")
+	previewValidatorsFlags.Int(cfgPreviewMinValidators, 0, "minimum number of validators")
+	previewValidatorsFlags.Int(cfgPreviewMaxValidatorsPerEntity, 0, "maximum number of validators per entity")
+	previewValidatorsFlags.StringSlice(cfgPreviewEntitiesDirPaths, []string{},
+		"directories of entity packages used to name entities in the report")
+	_ = viper.BindPFlags(previewValidatorsFlags)
+
+	previewValidatorsCmd.Flags().AddFlagSet(previewValidatorsFlags)
+
+	parentCmd.AddCommand(previewValidatorsCmd)
+}
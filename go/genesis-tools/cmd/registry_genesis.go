@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/registrygenesis"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+const (
+	cfgRegistryEntitiesDirPaths   = "registry.entities_dir"
+	cfgRegistryEntityOnlyDirPaths = "registry.entity_only_dir"
+	cfgRegistryNetworkParamsPath  = "registry.network_params"
+	cfgRegistryNodePolicyPath     = "registry.node_policy"
+	cfgRegistryOutputPath         = "registry.output_path"
+)
+
+var (
+	registryGenesisCmd = &cobra.Command{
+		Use:   "registry_genesis",
+		Short: "Generates the registry section of genesis",
+		Long: `Generates the registry section of genesis
+
+        Uses a directory of unpacked Entity Packages. Entities and their
+        nodes are registered, nodes violating the node policy are left
+        out. Packages in the entity only directories are registered
+        without their nodes. The registry consensus parameters are the
+        registry section of the network params.`,
+		Run: doRegistryGenesis,
+	}
+
+	registryGenesisFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func loadRegistryPackages(paths []string) ([]*stakinggenesis.EntityPackage, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	entitiesDir, err := stakinggenesis.LoadEntitiesDirectory(paths)
+	if err != nil {
+		return nil, err
+	}
+	return entitiesDir.Packages(), nil
+}
+
+func doRegistryGenesis(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	entitiesDirPaths := viper.GetStringSlice(cfgRegistryEntitiesDirPaths)
+	if len(entitiesDirPaths) < 1 {
+		logger.Error("must define an entities directory path")
+		os.Exit(1)
+	}
+	packages, err := loadRegistryPackages(entitiesDirPaths)
+	if err != nil {
+		logLoadErrors(err)
+		logger.Error("Cannot load entities")
+		os.Exit(1)
+	}
+	entityOnlyPackages, err := loadRegistryPackages(viper.GetStringSlice(cfgRegistryEntityOnlyDirPaths))
+	if err != nil {
+		logLoadErrors(err)
+		logger.Error("Cannot load entity only entities")
+		os.Exit(1)
+	}
+
+	networkParamsPath := viper.GetString(cfgRegistryNetworkParamsPath)
+	if networkParamsPath == "" {
+		logger.Error("must set the network params path")
+		os.Exit(1)
+	}
+	params, err := loadNetworkParams(networkParamsPath)
+	if err != nil {
+		logger.Error("failed to load the network params",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	options := registrygenesis.GenesisOptions{
+		Packages:           packages,
+		EntityOnlyPackages: entityOnlyPackages,
+		ConsensusParametersLoader: func() registry.ConsensusParameters {
+			return params.Registry
+		},
+	}
+	if nodePolicyPath := viper.GetString(cfgRegistryNodePolicyPath); nodePolicyPath != "" {
+		options.NodePolicy, err = stakinggenesis.LoadNodePolicy(nodePolicyPath)
+		if err != nil {
+			logger.Error("failed to load the node policy",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
+
+	outputPath := viper.GetString(cfgRegistryOutputPath)
+	if outputPath == "" {
+		logger.Error("must set output path for registry genesis file")
+		os.Exit(1)
+	}
+
+	registryGenesis, err := registrygenesis.Create(options)
+	if err != nil {
+		logKeyConflicts(err)
+		logger.Error("failed to create a registry genesis file",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	b, err := json.Marshal(registryGenesis)
+	if err == nil {
+		err = ioutil.WriteFile(outputPath, b, 0644)
+	}
+	if err != nil {
+		logger.Error("failed to write registry genesis to json",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+// logKeyConflicts logs every package involved in a key conflict.
+func logKeyConflicts(err error) {
+	var dupErr *stakinggenesis.DuplicateKeysError
+	if !errors.As(err, &dupErr) {
+		return
+	}
+	for _, conflict := range dupErr.Conflicts {
+		logger.Error("key is shared between entity packages",
+			"kind", conflict.Kind,
+			"key", conflict.Key,
+			"packages", strings.Join(conflict.Packages, ", "),
+		)
+	}
+}
+
+// RegisterRegistryGenesisCmd registers the registry_genesis subcommand.
+func RegisterRegistryGenesisCmd(parentCmd *cobra.Command) {
+	registryGenesisFlags.StringSlice(cfgRegistryEntitiesDirPaths, []string{},
+		"directories of entity packages registered with their nodes")
+	registryGenesisFlags.StringSlice(cfgRegistryEntityOnlyDirPaths, []string{},
+		"directories of entity packages registered without their nodes")
+	registryGenesisFlags.String(cfgRegistryNetworkParamsPath, "",
+		"a network params json file whose registry section is used as the registry consensus params")
+	registryGenesisFlags.String(cfgRegistryNodePolicyPath, "",
+		"a yaml node policy, nodes violating it are not registered")
+	registryGenesisFlags.String(cfgRegistryOutputPath, "", "output path for the registry genesis")
+	_ = viper.BindPFlags(registryGenesisFlags)
+
+	registryGenesisCmd.Flags().AddFlagSet(registryGenesisFlags)
+
+	parentCmd.AddCommand(registryGenesisCmd)
+}
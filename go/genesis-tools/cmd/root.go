@@ -50,4 +50,14 @@ func init() {
 
 	// Register all of the sub-commands.
 	RegisterStakingGenesisCmd(rootCmd)
+	RegisterRegistryGenesisCmd(rootCmd)
+	RegisterPreviewValidatorsCmd(rootCmd)
+	RegisterCheckGenesisCmd(rootCmd)
+	RegisterAssembleGenesisCmd(rootCmd)
+	RegisterNetworkParamsCmd(rootCmd)
+	RegisterValidateEntitiesCmd(rootCmd)
+	RegisterValidateSubmissionCmd(rootCmd)
+	RegisterInspectCmd(rootCmd)
+	RegisterAddressCmd(rootCmd)
+	RegisterApprovalCmds(rootCmd)
 }
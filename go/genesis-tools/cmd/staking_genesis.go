@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
@@ -11,15 +13,23 @@ import (
 
 	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
 	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
 )
 
 const (
-	cfgEntitiesDirPaths       = "staking.entities_dir"
-	cfgStakingParametersPath  = "staking.params"
-	cfgGenesisConfigPath      = "staking.config"
-	cfgGenesisAllocationsPath = "staking.allocations"
-	cfgTestOnlyGenesis        = "staking.test_only_genesis"
-	cfgOutputPath             = "output-path"
+	cfgEntitiesDirPaths         = "staking.entities_dir"
+	cfgStakingNetworkParamsPath = "staking.network_params"
+	cfgStakingBaseGenesisPath   = "staking.base_genesis"
+	cfgStakingAdjustmentsPath   = "staking.adjustments"
+	cfgStakingAdjustmentsReport = "staking.adjustments_report"
+	cfgStakingKYCReport         = "staking.kyc_report"
+	cfgGenesisConfigPath        = "staking.config"
+	cfgGenesisAllocationsPath   = "staking.allocations"
+	cfgTestOnlyGenesis          = "staking.test_only_genesis"
+	cfgRequireGithubHandles     = "staking.require_github_handles"
+	cfgSkipInvalid              = "staking.skip_invalid"
+	cfgExclusionListPath        = "staking.exclusion_list"
+	cfgOutputPath               = "output-path"
 )
 
 var (
@@ -46,20 +56,66 @@ func doStakingGenesis(cmd *cobra.Command, args []string) {
 		logger.Error("must define an entities directory path")
 		os.Exit(1)
 	}
-	entitiesDir, err := stakinggenesis.LoadEntitiesDirectory(entitiesDirPaths)
+	entitiesDir, err := stakinggenesis.LoadEntitiesDirectoryWithOptions(entitiesDirPaths, stakinggenesis.EntitiesDirectoryOptions{
+		NamePolicy:  stakinggenesis.DefaultPackageNamePolicy(),
+		Versions:    stakinggenesis.DefaultDescriptorVersions(),
+		SkipInvalid: viper.GetBool(cfgSkipInvalid),
+	})
 	if err != nil {
-		logger.Error("Cannot load entities",
+		logLoadErrors(err)
+		logger.Error("Cannot load entities")
+		os.Exit(1)
+	}
+
+	excluded := entitiesDir.Excluded()
+	if len(excluded) > 0 {
+		logLoadErrors(excluded)
+		logger.Warn("excluding invalid entity packages from the staking ledger",
+			"excluded", strings.Join(excluded.Names(), ","),
+		)
+	}
+	if exclusionListPath := viper.GetString(cfgExclusionListPath); exclusionListPath != "" {
+		if err = writeExclusionList(exclusionListPath, excluded); err != nil {
+			logger.Error("failed to write the exclusion list",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
+
+	options := stakinggenesis.GenesisOptions{
+		Entities:             entitiesDir,
+		ConfigurationPath:    viper.GetString(cfgGenesisConfigPath),
+		IsTestGenesis:        viper.GetBool(cfgTestOnlyGenesis),
+		RequireGithubHandles: viper.GetBool(cfgRequireGithubHandles),
+		AllocationsPath:      viper.GetString(cfgGenesisAllocationsPath),
+		ExcludedEntities:     excluded.Names(),
+		AdjustmentsPath:      viper.GetString(cfgStakingAdjustmentsPath),
+	}
+
+	networkParamsPath := viper.GetString(cfgStakingNetworkParamsPath)
+	if networkParamsPath == "" {
+		logger.Error("must set the network params path")
+		os.Exit(1)
+	}
+	params, err := loadNetworkParams(networkParamsPath)
+	if err != nil {
+		logger.Error("failed to load the network params",
 			"err", err,
 		)
 		os.Exit(1)
 	}
+	options.ConsensusParametersLoader = func() staking.ConsensusParameters {
+		return params.Staking
+	}
 
-	options := stakinggenesis.GenesisOptions{
-		Entities:                entitiesDir,
-		ConsensusParametersPath: viper.GetString(cfgStakingParametersPath),
-		ConfigurationPath:       viper.GetString(cfgGenesisConfigPath),
-		IsTestGenesis:           viper.GetBool(cfgTestOnlyGenesis),
-		AllocationsPath:         viper.GetString(cfgGenesisAllocationsPath),
+	if baseGenesisPath := viper.GetString(cfgStakingBaseGenesisPath); baseGenesisPath != "" {
+		if options.BaseGenesis, _, err = stakinggenesis.LoadStakingGenesis(baseGenesisPath); err != nil {
+			logger.Error("failed to load the base staking genesis",
+				"err", err,
+			)
+			os.Exit(1)
+		}
 	}
 
 	outputPath := viper.GetString(cfgOutputPath)
@@ -68,13 +124,52 @@ func doStakingGenesis(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	stakingGenesis, err := stakinggenesis.Create(options)
+	stakingGenesis, report, err := stakinggenesis.CreateWithReport(options)
 	if err != nil {
 		logger.Error("failed to create a staking genesis file",
 			"err", err,
 		)
 		os.Exit(1)
 	}
+	for _, result := range report.Adjustments {
+		logger.Info("applied ledger adjustment",
+			"index", result.Index,
+			"op", result.Op,
+			"reason", result.Reason,
+			"changes", len(result.Changes),
+		)
+	}
+	if reportPath := viper.GetString(cfgStakingAdjustmentsReport); reportPath != "" {
+		b, err := json.MarshalIndent(report.Adjustments, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(reportPath, b, 0o644)
+		}
+		if err != nil {
+			logger.Error("failed to write the adjustments report",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
+
+	for _, block := range report.KYC {
+		logger.Warn("allocation blocked by KYC",
+			"entity_name", block.Entity,
+			"status", block.Status,
+			"allocation", block.Allocation,
+			"account", block.Account,
+			"amount", block.Amount,
+			"redirected_to", block.RedirectedTo,
+		)
+	}
+	if kycReportPath := viper.GetString(cfgStakingKYCReport); kycReportPath != "" {
+		if err = writeKYCReport(kycReportPath, report.KYC); err != nil {
+			logger.Error("failed to write the KYC report",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
 
 	b, err := json.Marshal(stakingGenesis)
 	err = ioutil.WriteFile(outputPath, b, 0644)
@@ -86,17 +181,75 @@ func doStakingGenesis(cmd *cobra.Command, args []string) {
 	}
 }
 
+// logLoadErrors logs every entity package failure.
+func logLoadErrors(err error) {
+	var loadErrs stakinggenesis.LoadErrors
+	if !errors.As(err, &loadErrs) {
+		logger.Error("invalid entity packages",
+			"err", err,
+		)
+		return
+	}
+	for _, loadErr := range loadErrs {
+		logger.Error("invalid entity package",
+			"path", loadErr.Path,
+			"category", loadErr.Category,
+			"err", loadErr.Err,
+		)
+	}
+}
+
+func writeExclusionList(path string, excluded stakinggenesis.LoadErrors) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = excluded.WriteCSV(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func writeKYCReport(path string, report stakinggenesis.KYCReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = report.WriteCSV(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 // RegisterStakingGenesisCmd registers the for-testing subcommand.
 func RegisterStakingGenesisCmd(parentCmd *cobra.Command) {
 	stakingGenesisFlags.StringSlice(cfgEntitiesDirPaths, []string{}, "a directory entities")
-	stakingGenesisFlags.String(cfgStakingParametersPath, "",
-		"a consensus params json file (defaults to using ./consensus_params.json relative to entities directory)")
+	stakingGenesisFlags.String(cfgStakingNetworkParamsPath, "",
+		"a network params json file whose staking section is used as the staking consensus params")
+	stakingGenesisFlags.String(cfgStakingBaseGenesisPath, "",
+		"a staking genesis or genesis document json file, like a state dump, whose ledger is kept and extended")
+	stakingGenesisFlags.String(cfgStakingAdjustmentsPath, "",
+		"a yaml file of ledger adjustments applied after the allocations")
+	stakingGenesisFlags.String(cfgStakingAdjustmentsReport, "",
+		"output path for a json report of the balances changed by each adjustment")
+	stakingGenesisFlags.String(cfgStakingKYCReport, "",
+		"output path for a csv report of the allocations blocked by KYC")
 	stakingGenesisFlags.String(cfgGenesisConfigPath, "",
 		"a yaml file used to establish fund and delegation configuration on the staking ledger")
 	stakingGenesisFlags.String(cfgGenesisAllocationsPath, "",
 		"a csv file used to establish fund and delegation allocation on the staking ledger")
 	stakingGenesisFlags.String(cfgOutputPath, "", "output path for the staking ledger")
 	stakingGenesisFlags.Bool(cfgTestOnlyGenesis, false, "generate a test staking ledger")
+	stakingGenesisFlags.Bool(cfgSkipInvalid, false,
+		"exclude invalid entity packages from the staking ledger instead of failing")
+	stakingGenesisFlags.String(cfgExclusionListPath, "",
+		"output path for a csv list of the excluded entity packages")
+	stakingGenesisFlags.Bool(cfgRequireGithubHandles, false,
+		"require every entity package name to appear in the allocations github handle column")
 	_ = viper.BindPFlags(stakingGenesisFlags)
 
 	stakingGenesisCmd.Flags().AddFlagSet(stakingGenesisFlags)
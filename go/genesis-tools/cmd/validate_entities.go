@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+)
+
+const (
+	cfgValidateEntitiesDirPaths = "entities.dir"
+	cfgValidatePackagesDirPath  = "entities.packages_dir"
+	cfgValidateConfigPath       = "entities.config"
+	cfgValidateAllocationsPath  = "entities.allocations"
+	cfgValidateNodePolicyPath   = "entities.node_policy"
+	cfgValidateNetworkParams    = "entities.network_params"
+	cfgValidateWorkers          = "entities.workers"
+)
+
+var (
+	validateEntitiesCmd = &cobra.Command{
+		Use:   "validate-entities",
+		Short: "Validates a set of entity packages",
+		Long: `Validates a set of entity packages
+
+        Uses a directory of unpacked Entity Packages. Optionally checks the
+        packed archive names, that every package name is a github handle
+        in the allocations table and that node descriptors follow a node
+        policy.`,
+		Run: doValidateEntities,
+	}
+
+	validateEntitiesFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doValidateEntities(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	entitiesDirPaths := viper.GetStringSlice(cfgValidateEntitiesDirPaths)
+	if len(entitiesDirPaths) < 1 {
+		logger.Error("must define an entities directory path")
+		os.Exit(1)
+	}
+
+	var problems []error
+	policy := stakinggenesis.DefaultPackageNamePolicy()
+
+	if packagesDirPath := viper.GetString(cfgValidatePackagesDirPath); packagesDirPath != "" {
+		if err := validatePackageFileNames(policy, packagesDirPath); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	entitiesDir, err := stakinggenesis.LoadEntitiesDirectoryWithOptions(entitiesDirPaths, stakinggenesis.EntitiesDirectoryOptions{
+		NamePolicy: policy,
+		Versions:   stakinggenesis.DefaultDescriptorVersions(),
+		Workers:    viper.GetInt(cfgValidateWorkers),
+	})
+	var loadErrs stakinggenesis.LoadErrors
+	switch {
+	case errors.As(err, &loadErrs):
+		for _, loadErr := range loadErrs {
+			problems = append(problems, loadErr)
+		}
+	case err != nil:
+		problems = append(problems, err)
+	}
+
+	allocationsPath := viper.GetString(cfgValidateAllocationsPath)
+	if entitiesDir != nil && allocationsPath != "" {
+		if err = checkGithubHandles(entitiesDir, viper.GetString(cfgValidateConfigPath), allocationsPath); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if nodePolicyPath := viper.GetString(cfgValidateNodePolicyPath); entitiesDir != nil && nodePolicyPath != "" {
+		nodePolicy, err := stakinggenesis.LoadNodePolicy(nodePolicyPath)
+		if err != nil {
+			logger.Error("failed to load the node policy",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		networkParamsPath := viper.GetString(cfgValidateNetworkParams)
+		if networkParamsPath == "" {
+			logger.Error("must set the network params path with a node policy")
+			os.Exit(1)
+		}
+		params, err := loadNetworkParams(networkParamsPath)
+		if err != nil {
+			logger.Error("failed to load the network params",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		if nodePolicy, err = nodePolicy.WithRegistryParameters(params.Registry); err != nil {
+			logger.Error("the node policy does not match the network params",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		for _, violation := range nodePolicy.CheckAll(entitiesDir.Packages()) {
+			problems = append(problems, errors.New(violation.String()))
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("found %d problem(s) with the entity packages:\n", len(problems))
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("%d entity package(s) are valid\n", len(entitiesDir.All()))
+}
+
+func validatePackageFileNames(policy stakinggenesis.PackageNamePolicy, dirPath string) error {
+	files, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return err
+	}
+	var fileNames []string
+	for _, fileInfo := range files {
+		// The package directory also holds a README and similar files.
+		if fileInfo.IsDir() || fileInfo.Name() == "README.md" {
+			continue
+		}
+		fileNames = append(fileNames, fileInfo.Name())
+	}
+	return policy.ValidatePackageFileNames(fileNames)
+}
+
+func checkGithubHandles(entities stakinggenesis.Entities, configPath, allocationsPath string) error {
+	config, err := stakinggenesis.LoadGenesisConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if config.CSVOptions.GithubHandleLabel == "" {
+		return fmt.Errorf("github handle check requires csv_options.github_handle_label")
+	}
+	table, err := stakinggenesis.LoadEntityAllocationTable(allocationsPath, config)
+	if err != nil {
+		return err
+	}
+	return stakinggenesis.CheckGithubHandles(entities, table.GithubHandles())
+}
+
+// RegisterValidateEntitiesCmd registers the validate-entities subcommand.
+func RegisterValidateEntitiesCmd(parentCmd *cobra.Command) {
+	validateEntitiesFlags.StringSlice(cfgValidateEntitiesDirPaths, []string{}, "a directory of unpacked entities")
+	validateEntitiesFlags.String(cfgValidatePackagesDirPath, "",
+		"a directory of packed entity packages whose file names are checked")
+	validateEntitiesFlags.String(cfgValidateConfigPath, "",
+		"a staking ledger yaml file (required with --entities.allocations)")
+	validateEntitiesFlags.String(cfgValidateAllocationsPath, "",
+		"a csv allocations file used to check package names against github handles")
+	validateEntitiesFlags.String(cfgValidateNodePolicyPath, "",
+		"a yaml node policy file that every node descriptor is checked against")
+	validateEntitiesFlags.String(cfgValidateNetworkParams, "",
+		"a network params json file whose registry max_node_expiration the node policy checks (required with --entities.node_policy)")
+	validateEntitiesFlags.Int(cfgValidateWorkers, 0,
+		"number of packages loaded concurrently (defaults to the number of CPUs)")
+	_ = viper.BindPFlags(validateEntitiesFlags)
+
+	validateEntitiesCmd.Flags().AddFlagSet(validateEntitiesFlags)
+
+	parentCmd.AddCommand(validateEntitiesCmd)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/submission"
+	nodeCmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+)
+
+const (
+	cfgSubmissionRepoPath      = "submission.repo"
+	cfgSubmissionBaseRef       = "submission.base"
+	cfgSubmissionHeadRef       = "submission.head"
+	cfgSubmissionAuthor        = "submission.author"
+	cfgSubmissionNodePolicy    = "submission.node_policy"
+	cfgSubmissionNetworkParams = "submission.network_params"
+)
+
+var (
+	validateSubmissionCmd = &cobra.Command{
+		Use:   "validate-submission",
+		Short: "Validates an entity package submission",
+		Long: `Validates an entity package submission
+
+        Uses the local git repository to find the files changed between the
+        base and head refs. Only entities/<author>-entity.tar.gz may be
+        changed, and the submitted package must be a valid entity package.`,
+		Run: doValidateSubmission,
+	}
+
+	validateSubmissionFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doValidateSubmission(cmd *cobra.Command, args []string) {
+	if err := nodeCmdCommon.Init(); err != nil {
+		nodeCmdCommon.EarlyLogAndExit(err)
+	}
+
+	options := submission.Options{
+		RepositoryPath: viper.GetString(cfgSubmissionRepoPath),
+		BaseRef:        viper.GetString(cfgSubmissionBaseRef),
+		HeadRef:        viper.GetString(cfgSubmissionHeadRef),
+		Author:         viper.GetString(cfgSubmissionAuthor),
+	}
+	if options.BaseRef == "" || options.Author == "" {
+		logger.Error("must set the base ref and the author of the submission")
+		os.Exit(1)
+	}
+
+	if nodePolicyPath := viper.GetString(cfgSubmissionNodePolicy); nodePolicyPath != "" {
+		nodePolicy, err := stakinggenesis.LoadNodePolicy(nodePolicyPath)
+		if err != nil {
+			logger.Error("failed to load the node policy",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		options.NodePolicy = nodePolicy
+
+		networkParamsPath := viper.GetString(cfgSubmissionNetworkParams)
+		if networkParamsPath == "" {
+			logger.Error("must set the network params path with a node policy")
+			os.Exit(1)
+		}
+		params, err := loadNetworkParams(networkParamsPath)
+		if err != nil {
+			logger.Error("failed to load the network params",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		options.RegistryParameters = &params.Registry
+	}
+
+	result, err := submission.Validate(options)
+	if err != nil {
+		logger.Error("failed to validate the submission",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	if !result.Valid() {
+		fmt.Printf("found %d problem(s) with the submission:\n", len(result.Problems))
+		for _, problem := range result.Problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("%s is a valid entity package for entity %s\n", result.PackagePath, result.Package.Entity.ID)
+}
+
+// RegisterValidateSubmissionCmd registers the validate-submission subcommand.
+func RegisterValidateSubmissionCmd(parentCmd *cobra.Command) {
+	validateSubmissionFlags.String(cfgSubmissionRepoPath, ".", "path of the git repository")
+	validateSubmissionFlags.String(cfgSubmissionBaseRef, "", "the git ref the submission is merged into")
+	validateSubmissionFlags.String(cfgSubmissionHeadRef, "HEAD", "the git ref of the submission")
+	validateSubmissionFlags.String(cfgSubmissionAuthor, "", "the github handle of the submitter")
+	validateSubmissionFlags.String(cfgSubmissionNodePolicy, "",
+		"a yaml node policy file that the submitted node descriptor is checked against")
+	validateSubmissionFlags.String(cfgSubmissionNetworkParams, "",
+		"a network params json file whose registry max_node_expiration the node policy checks (required with --submission.node_policy)")
+	_ = viper.BindPFlags(validateSubmissionFlags)
+
+	validateSubmissionCmd.Flags().AddFlagSet(validateSubmissionFlags)
+
+	parentCmd.AddCommand(validateSubmissionCmd)
+}
@@ -0,0 +1,139 @@
+package genesischeck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
+)
+
+// Sections of the genesis document, in the order they are checked.
+const (
+	SectionDocument   = "document"
+	SectionConsensus  = "consensus"
+	SectionEpochTime  = "epochtime"
+	SectionRegistry   = "registry"
+	SectionRootHash   = "roothash"
+	SectionStaking    = "staking"
+	SectionKeyManager = "keymanager"
+	SectionScheduler  = "scheduler"
+	SectionBeacon     = "beacon"
+	SectionHaltEpoch  = "halt_epoch"
+)
+
+// SectionError is a sanity check failure of a genesis document section.
+type SectionError struct {
+	Section string
+	Err     error
+}
+
+func (e *SectionError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Section, e.Err)
+}
+
+func (e *SectionError) Unwrap() error {
+	return e.Err
+}
+
+// Errors are the sanity check failures of a genesis document.
+type Errors []*SectionError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d genesis section(s) failed the sanity check: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Sections returns the names of the failed sections.
+func (e Errors) Sections() []string {
+	sections := make([]string, 0, len(e))
+	for _, err := range e {
+		sections = append(sections, err.Section)
+	}
+	return sections
+}
+
+// Check runs the oasis-core sanity checks on every section of a genesis
+// document. Unlike genesis.Document.SanityCheck, which stops at the first
+// failure, every section is checked and every failure is returned.
+func Check(doc *genesis.Document) error {
+	var errs Errors
+	check := func(section string, err error) {
+		if err != nil {
+			errs = append(errs, &SectionError{Section: section, Err: err})
+		}
+	}
+
+	if doc.Height < 1 {
+		check(SectionDocument, fmt.Errorf("height must be >= 1"))
+	}
+	if strings.TrimSpace(doc.ChainID) == "" {
+		check(SectionDocument, fmt.Errorf("chain ID must not be empty"))
+	}
+
+	check(SectionConsensus, doc.Consensus.SanityCheck())
+	pkBlacklist := make(map[signature.PublicKey]bool)
+	for _, v := range doc.Consensus.Parameters.PublicKeyBlacklist {
+		pkBlacklist[v] = true
+	}
+
+	check(SectionEpochTime, doc.EpochTime.SanityCheck())
+	check(SectionRegistry, doc.Registry.SanityCheck(doc.EpochTime.Base, doc.Staking.Ledger, doc.Staking.Parameters.Thresholds, pkBlacklist))
+	check(SectionRootHash, doc.RootHash.SanityCheck())
+	check(SectionStaking, doc.Staking.SanityCheck(doc.EpochTime.Base))
+	check(SectionKeyManager, doc.KeyManager.SanityCheck())
+	check(SectionScheduler, doc.Scheduler.SanityCheck(&doc.Staking.TotalSupply))
+	check(SectionBeacon, doc.Beacon.SanityCheck())
+
+	if doc.HaltEpoch < doc.EpochTime.Base {
+		check(SectionHaltEpoch, fmt.Errorf("halt epoch %d is before the base epoch %d", doc.HaltEpoch, doc.EpochTime.Base))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// LoadDocument loads a genesis document without sanity checking it. Decoding
+// errors report the line and column of the problem.
+func LoadDocument(path string) (*genesis.Document, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc genesis.Document
+	if err = json.Unmarshal(b, &doc); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			line, col := position(b, syntaxErr.Offset)
+			return nil, fmt.Errorf("%s:%d:%d: malformed genesis document: %w", path, line, col, err)
+		case errors.As(err, &typeErr):
+			line, col := position(b, typeErr.Offset)
+			return nil, fmt.Errorf("%s:%d:%d: malformed genesis document: %w", path, line, col, err)
+		}
+		return nil, fmt.Errorf("%s: malformed genesis document: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// position returns the line and column of a byte offset.
+func position(b []byte, offset int64) (int, int) {
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	before := b[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
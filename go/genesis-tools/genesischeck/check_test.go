@@ -0,0 +1,95 @@
+package genesischeck_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/genesis"
+	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// validDocument returns a minimal genesis document that passes the sanity
+// checks.
+func validDocument() *genesis.Document {
+	doc := &genesis.Document{
+		Height:    1,
+		ChainID:   "test",
+		HaltEpoch: 100,
+	}
+	doc.EpochTime.Parameters.Interval = 600
+	doc.Consensus.Parameters = consensus.Parameters{TimeoutCommit: 5 * time.Second}
+	doc.Registry.Parameters = registry.ConsensusParameters{MaxNodeExpiration: 2}
+
+	thresholds := make(map[staking.ThresholdKind]quantity.Quantity)
+	for kind := staking.KindEntity; kind <= staking.KindMax; kind++ {
+		thresholds[kind] = *quantity.NewFromUint64(100)
+	}
+	doc.Staking = staking.Genesis{
+		Parameters: staking.ConsensusParameters{
+			Thresholds:         thresholds,
+			FeeSplitWeightVote: *quantity.NewFromUint64(1),
+		},
+		TokenSymbol: "TEST",
+		TotalSupply: *quantity.NewFromUint64(1000),
+		CommonPool:  *quantity.NewFromUint64(1000),
+	}
+	return doc
+}
+
+func TestCheck(t *testing.T) {
+	require.NoError(t, genesischeck.Check(validDocument()))
+
+	doc := validDocument()
+	doc.ChainID = " "
+	doc.EpochTime.Parameters.Interval = 0
+	doc.Registry.Parameters.MaxNodeExpiration = 0
+	doc.Staking.CommonPool = *quantity.NewFromUint64(999)
+	doc.HaltEpoch = 0
+	doc.EpochTime.Base = 1
+
+	err := genesischeck.Check(doc)
+	var errs genesischeck.Errors
+	require.True(t, errors.As(err, &errs))
+	require.Equal(t, []string{
+		genesischeck.SectionDocument,
+		genesischeck.SectionEpochTime,
+		genesischeck.SectionRegistry,
+		genesischeck.SectionStaking,
+		genesischeck.SectionHaltEpoch,
+	}, errs.Sections())
+	require.Contains(t, errs[1].Error(), "epoch interval must be > 0")
+	require.Contains(t, errs[2].Error(), "maximum node expiration not specified")
+	require.Contains(t, errs[3].Error(), "does not add up to total supply")
+}
+
+func TestLoadDocument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesischeck")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("{\n  \"height\": 1,\n  \"chain_id\": 2\n}\n"), 0o644))
+	_, err = genesischeck.LoadDocument(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "genesis.json:3:")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("{\n  \"height\": 1,\n  \"chain_id\" \"test\"\n}\n"), 0o644))
+	_, err = genesischeck.LoadDocument(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "genesis.json:3:")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"height": 1, "chain_id": "test"}`), 0o644))
+	doc, err := genesischeck.LoadDocument(path)
+	require.NoError(t, err)
+	require.Equal(t, "test", doc.ChainID)
+}
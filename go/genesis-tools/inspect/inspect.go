@@ -0,0 +1,158 @@
+// Package inspect presents the accounts of a staking genesis for humans.
+package inspect
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// Roles of the accounts of a staking genesis, in the order they are
+// reported.
+const (
+	RoleFoundation = "foundation"
+	RoleEntity     = "entity"
+	RoleUnknown    = "unknown"
+)
+
+// Roles are the account roles in the order they are reported.
+var Roles = []string{RoleFoundation, RoleEntity, RoleUnknown}
+
+// Orders the accounts can be sorted in. Amounts are sorted largest first.
+const (
+	SortAddress = "address"
+	SortName    = "name"
+	SortGeneral = "general"
+	SortEscrow  = "escrow"
+	SortTotal   = "total"
+)
+
+// SortOrders are the known sort orders.
+var SortOrders = []string{SortAddress, SortName, SortGeneral, SortEscrow, SortTotal}
+
+// Account is an account of the ledger with its role and name.
+type Account struct {
+	Address staking.Address
+	Role    string
+	// Name is the name of the foundation account or entity package, empty
+	// for unknown accounts.
+	Name string
+
+	General   quantity.Quantity
+	Escrow    quantity.Quantity
+	Debonding quantity.Quantity
+	// Total is the sum of the general, escrow and debonding balances.
+	Total quantity.Quantity
+	// Delegators is the number of accounts delegating to the escrow.
+	Delegators int
+}
+
+// Options options for inspecting a staking genesis.
+type Options struct {
+	// Foundation maps the addresses of the foundation accounts, like the
+	// accounts of the staking configuration, to their names.
+	Foundation map[staking.Address]string
+	// Entities maps the addresses of entities to their package names.
+	Entities map[staking.Address]string
+
+	// Addresses only keeps the accounts with one of the addresses.
+	Addresses []staking.Address
+	// Names only keeps the accounts with one of the names.
+	Names []string
+	// MinStake only keeps the accounts with at least this escrow, in base
+	// units.
+	MinStake *quantity.Quantity
+	// SortBy is one of SortOrders, it defaults to SortAddress.
+	SortBy string
+}
+
+// Accounts returns the accounts of the ledger that pass the filters of the
+// options, sorted.
+func Accounts(genesis *staking.Genesis, options Options) ([]*Account, error) {
+	less, err := lessFunc(options.SortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[staking.Address]bool)
+	for _, address := range options.Addresses {
+		addresses[address] = true
+	}
+	names := make(map[string]bool)
+	for _, name := range options.Names {
+		names[strings.ToLower(name)] = true
+	}
+
+	var accounts []*Account
+	for address, ledgerAccount := range genesis.Ledger {
+		account, err := newAccount(address, ledgerAccount, options)
+		if err != nil {
+			return nil, err
+		}
+		account.Delegators = len(genesis.Delegations[address])
+
+		if len(addresses) > 0 && !addresses[address] {
+			continue
+		}
+		if len(names) > 0 && !names[strings.ToLower(account.Name)] {
+			continue
+		}
+		if options.MinStake != nil && account.Escrow.Cmp(options.MinStake) < 0 {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		if c := less(accounts[i], accounts[j]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(accounts[i].Address[:], accounts[j].Address[:]) < 0
+	})
+	return accounts, nil
+}
+
+func newAccount(address staking.Address, ledgerAccount *staking.Account, options Options) (*Account, error) {
+	account := &Account{
+		Address:   address,
+		Role:      RoleUnknown,
+		General:   ledgerAccount.General.Balance,
+		Escrow:    ledgerAccount.Escrow.Active.Balance,
+		Debonding: ledgerAccount.Escrow.Debonding.Balance,
+	}
+	if name, ok := options.Foundation[address]; ok {
+		account.Role, account.Name = RoleFoundation, name
+	} else if name, ok := options.Entities[address]; ok {
+		account.Role, account.Name = RoleEntity, name
+	}
+
+	for _, balance := range []*quantity.Quantity{&account.General, &account.Escrow, &account.Debonding} {
+		if err := account.Total.Add(balance); err != nil {
+			return nil, fmt.Errorf("%s: %w", address, err)
+		}
+	}
+	return account, nil
+}
+
+// lessFunc returns a comparison of accounts for the sort order, ties are
+// broken by address.
+func lessFunc(sortBy string) (func(a, b *Account) int, error) {
+	switch sortBy {
+	case "", SortAddress:
+		return func(a, b *Account) int { return 0 }, nil
+	case SortName:
+		return func(a, b *Account) int { return strings.Compare(a.Name, b.Name) }, nil
+	case SortGeneral:
+		return func(a, b *Account) int { return b.General.Cmp(&a.General) }, nil
+	case SortEscrow:
+		return func(a, b *Account) int { return b.Escrow.Cmp(&a.Escrow) }, nil
+	case SortTotal:
+		return func(a, b *Account) int { return b.Total.Cmp(&a.Total) }, nil
+	default:
+		return nil, fmt.Errorf("unknown sort order %q, must be one of %s", sortBy, strings.Join(SortOrders, ", "))
+	}
+}
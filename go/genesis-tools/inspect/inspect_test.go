@@ -0,0 +1,158 @@
+package inspect_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/inspect"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+func testAddress(name string) staking.Address {
+	return staking.NewAddress(memorySigner.NewTestSigner(name).Public())
+}
+
+// testGenesis returns a ledger of a foundation account, two entities and an
+// unknown account, in tokens of 1000 base units.
+func testGenesis() *staking.Genesis {
+	account := func(general, escrow, debonding uint64) *staking.Account {
+		var a staking.Account
+		a.General.Balance = *quantity.NewFromUint64(general)
+		a.Escrow.Active.Balance = *quantity.NewFromUint64(escrow)
+		a.Escrow.Debonding.Balance = *quantity.NewFromUint64(debonding)
+		return &a
+	}
+	return &staking.Genesis{
+		TokenSymbol:        "ROSE",
+		TokenValueExponent: 3,
+		TotalSupply:        *quantity.NewFromUint64(1000000),
+		CommonPool:         *quantity.NewFromUint64(900000),
+		Ledger: map[staking.Address]*staking.Account{
+			testAddress("foundation"): account(50000, 0, 0),
+			testAddress("entity1"):    account(100, 30000, 0),
+			testAddress("entity2"):    account(9000, 1500, 500),
+			testAddress("unknown"):    account(7400, 0, 0),
+		},
+		Delegations: map[staking.Address]map[staking.Address]*staking.Delegation{
+			testAddress("entity1"): {
+				testAddress("entity1"):    {},
+				testAddress("foundation"): {},
+			},
+		},
+	}
+}
+
+func testOptions() inspect.Options {
+	return inspect.Options{
+		Foundation: map[staking.Address]string{testAddress("foundation"): "foundation"},
+		Entities: map[staking.Address]string{
+			testAddress("entity1"): "entity1",
+			testAddress("entity2"): "entity2",
+		},
+	}
+}
+
+func names(accounts []*inspect.Account) []string {
+	var names []string
+	for _, account := range accounts {
+		names = append(names, account.Name)
+	}
+	return names
+}
+
+func TestAccounts(t *testing.T) {
+	options := testOptions()
+	options.SortBy = inspect.SortTotal
+	accounts, err := inspect.Accounts(testGenesis(), options)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foundation", "entity1", "entity2", ""}, names(accounts))
+
+	require.Equal(t, inspect.RoleFoundation, accounts[0].Role)
+	require.Equal(t, inspect.RoleEntity, accounts[1].Role)
+	require.Equal(t, 2, accounts[1].Delegators)
+	require.Equal(t, quantity.NewFromUint64(11000), &accounts[2].Total)
+	require.Equal(t, inspect.RoleUnknown, accounts[3].Role)
+	require.Equal(t, testAddress("unknown"), accounts[3].Address)
+}
+
+func TestAccountsSortOrders(t *testing.T) {
+	for _, tc := range []struct {
+		sortBy string
+		names  []string
+	}{
+		{inspect.SortName, []string{"", "entity1", "entity2", "foundation"}},
+		{inspect.SortGeneral, []string{"foundation", "entity2", "", "entity1"}},
+		{inspect.SortEscrow, []string{"entity1", "entity2"}},
+	} {
+		options := testOptions()
+		options.SortBy = tc.sortBy
+		accounts, err := inspect.Accounts(testGenesis(), options)
+		require.NoError(t, err, tc.sortBy)
+		require.Equal(t, tc.names, names(accounts)[:len(tc.names)], tc.sortBy)
+	}
+
+	options := testOptions()
+	options.SortBy = "balance"
+	_, err := inspect.Accounts(testGenesis(), options)
+	require.Error(t, err)
+}
+
+func TestAccountsFilters(t *testing.T) {
+	options := testOptions()
+	options.Addresses = []staking.Address{testAddress("unknown"), testAddress("entity2")}
+	accounts, err := inspect.Accounts(testGenesis(), options)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+
+	options = testOptions()
+	options.Names = []string{"Entity1", "foundation"}
+	accounts, err = inspect.Accounts(testGenesis(), options)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"entity1", "foundation"}, names(accounts))
+
+	options = testOptions()
+	options.MinStake = quantity.NewFromUint64(1500)
+	options.SortBy = inspect.SortEscrow
+	accounts, err = inspect.Accounts(testGenesis(), options)
+	require.NoError(t, err)
+	require.Equal(t, []string{"entity1", "entity2"}, names(accounts))
+
+	// Filters combine.
+	options.Names = []string{"entity2", "foundation"}
+	accounts, err = inspect.Accounts(testGenesis(), options)
+	require.NoError(t, err)
+	require.Equal(t, []string{"entity2"}, names(accounts))
+}
+
+func TestFormatAmount(t *testing.T) {
+	require.Equal(t, "1234.5 ROSE", inspect.FormatAmount(*quantity.NewFromUint64(1234500), 3, "ROSE"))
+	require.Equal(t, "0.000000001 ROSE", inspect.FormatAmount(*quantity.NewFromUint64(1), 9, "ROSE"))
+	require.Equal(t, "42.0", inspect.FormatAmount(*quantity.NewFromUint64(42), 0, ""))
+	require.Equal(t, "42 base units", inspect.FormatAmount(*quantity.NewFromUint64(42), 100, "ROSE"))
+}
+
+func TestWriteReport(t *testing.T) {
+	genesis := testGenesis()
+	options := testOptions()
+	options.SortBy = inspect.SortTotal
+	accounts, err := inspect.Accounts(genesis, options)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, inspect.WriteReport(&buf, genesis, accounts))
+	report := buf.String()
+	require.Contains(t, report, "total supply  1000.0 ROSE")
+	require.Contains(t, report, "foundation accounts: 1, total 50.0 ROSE")
+	require.Contains(t, report, "entity accounts: 2, total 41.1 ROSE")
+	require.Contains(t, report, "unknown accounts: 1, total 7.4 ROSE")
+	require.Contains(t, report, testAddress("unknown").String())
+
+	// Roles without accounts are left out.
+	buf.Reset()
+	require.NoError(t, inspect.WriteReport(&buf, genesis, accounts[:1]))
+	require.NotContains(t, buf.String(), "entity accounts")
+}
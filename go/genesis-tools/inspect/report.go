@@ -0,0 +1,74 @@
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+	"github.com/oasisprotocol/oasis-core/go/staking/api/token"
+)
+
+// FormatAmount formats an amount in base units as tokens with the symbol,
+// like "1234.5 ROSE". Amounts are left in base units if the exponent is
+// invalid.
+func FormatAmount(amount quantity.Quantity, tokenValueExponent uint8, tokenSymbol string) string {
+	tokens, err := token.ConvertToTokenAmount(amount, tokenValueExponent)
+	if err != nil {
+		return fmt.Sprintf("%s base units", amount)
+	}
+	if tokenSymbol == "" {
+		return tokens
+	}
+	return fmt.Sprintf("%s %s", tokens, tokenSymbol)
+}
+
+// WriteReport writes the totals of the genesis and the accounts grouped by
+// role as tables. Roles without accounts are left out.
+func WriteReport(w io.Writer, genesis *staking.Genesis, accounts []*Account) error {
+	format := func(amount quantity.Quantity) string {
+		return FormatAmount(amount, genesis.TokenValueExponent, genesis.TokenSymbol)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "total supply\t%s\n", format(genesis.TotalSupply))
+	fmt.Fprintf(tw, "common pool\t%s\n", format(genesis.CommonPool))
+	fmt.Fprintf(tw, "accounts\t%d\n", len(genesis.Ledger))
+
+	groups := make(map[string][]*Account)
+	for _, account := range accounts {
+		groups[account.Role] = append(groups[account.Role], account)
+	}
+	for _, role := range Roles {
+		group := groups[role]
+		if len(group) == 0 {
+			continue
+		}
+
+		var total quantity.Quantity
+		for _, account := range group {
+			if err := total.Add(&account.Total); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(tw, "\n%s accounts: %d, total %s\n", role, len(group), format(total))
+		fmt.Fprintln(tw, "name\taddress\tgeneral\tescrow\tdebonding\ttotal\tdelegators")
+		for _, account := range group {
+			name := account.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+				name,
+				account.Address,
+				format(account.General),
+				format(account.Escrow),
+				format(account.Debonding),
+				format(account.Total),
+				account.Delegators,
+			)
+		}
+	}
+	return tw.Flush()
+}
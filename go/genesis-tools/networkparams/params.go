@@ -0,0 +1,197 @@
+// Package networkparams loads the consensus parameters the network launches
+// with from a single typed configuration file.
+package networkparams
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	consensusGenesis "github.com/oasisprotocol/oasis-core/go/consensus/genesis"
+	epochtime "github.com/oasisprotocol/oasis-core/go/epochtime/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// ConsensusBackend is the consensus backend of the network.
+const ConsensusBackend = "tendermint"
+
+// NetworkParams are the consensus parameters of every genesis section. The
+// sections and field names are those of the genesis document.
+type NetworkParams struct {
+	Consensus consensusGenesis.Genesis      `json:"consensus"`
+	EpochTime epochtime.ConsensusParameters `json:"epochtime"`
+	Registry  registry.ConsensusParameters  `json:"registry"`
+	RootHash  roothash.ConsensusParameters  `json:"roothash"`
+	Staking   staking.ConsensusParameters   `json:"staking"`
+	Scheduler scheduler.ConsensusParameters `json:"scheduler"`
+	Beacon    beacon.ConsensusParameters    `json:"beacon"`
+}
+
+// unknownFieldPrefix prefixes the quoted field name in the errors of a JSON
+// decoder that disallows unknown fields.
+const unknownFieldPrefix = "json: unknown field "
+
+// sections are the genesis sections every network params file must set.
+var sections = []string{
+	genesischeck.SectionConsensus,
+	genesischeck.SectionEpochTime,
+	genesischeck.SectionRegistry,
+	genesischeck.SectionRootHash,
+	genesischeck.SectionStaking,
+	genesischeck.SectionScheduler,
+	genesischeck.SectionBeacon,
+}
+
+// Load loads and validates a network params file. Unknown fields and missing
+// sections are rejected, decoding errors report the line and column of the
+// problem.
+func Load(path string) (*NetworkParams, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return nil, decodeError(path, b, err)
+	}
+	var missing []string
+	for _, section := range sections {
+		if _, ok := raw[section]; !ok {
+			missing = append(missing, section)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s: missing section(s) %v", path, missing)
+	}
+
+	var params NetworkParams
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&params); err != nil {
+		return nil, decodeError(path, b, err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("%s: trailing data after the network params", path)
+	}
+
+	if err = params.Validate(); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// decodeError adds the position of a decoding error. Unknown field errors do
+// not carry an offset, the first key with the field name is reported.
+func decodeError(path string, b []byte, err error) error {
+	offset := int64(-1)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		field := strings.TrimPrefix(err.Error(), unknownFieldPrefix)
+		if i := bytes.Index(b, []byte(field)); i >= 0 {
+			offset = int64(i)
+		}
+	}
+	if offset < 0 {
+		return fmt.Errorf("%s: malformed network params: %w", path, err)
+	}
+	line, col := position(b, offset)
+	return fmt.Errorf("%s:%d:%d: malformed network params: %w", path, line, col, err)
+}
+
+// position returns the line and column of a byte offset.
+func position(b []byte, offset int64) (int, int) {
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	before := b[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// Validate checks every section of the network params. Every section is
+// checked and the failures are returned as genesischeck.Errors.
+func (p *NetworkParams) Validate() error {
+	var errs genesischeck.Errors
+	check := func(section string, err error) {
+		if err != nil {
+			errs = append(errs, &genesischeck.SectionError{Section: section, Err: err})
+		}
+	}
+
+	consensus := p.Consensus.Parameters
+	if p.Consensus.Backend != ConsensusBackend {
+		check(genesischeck.SectionConsensus, fmt.Errorf("backend must be %q, not %q", ConsensusBackend, p.Consensus.Backend))
+	}
+	if consensus.MaxTxSize == 0 {
+		check(genesischeck.SectionConsensus, fmt.Errorf("max_tx_size must be > 0"))
+	}
+	if consensus.MaxBlockSize < consensus.MaxTxSize {
+		check(genesischeck.SectionConsensus, fmt.Errorf("max_block_size %d is smaller than max_tx_size %d",
+			consensus.MaxBlockSize, consensus.MaxTxSize))
+	}
+	check(genesischeck.SectionConsensus, p.Consensus.SanityCheck())
+
+	check(genesischeck.SectionEpochTime, (&epochtime.Genesis{Parameters: p.EpochTime}).SanityCheck())
+
+	check(genesischeck.SectionRegistry, (&registry.Genesis{Parameters: p.Registry}).SanityCheck(0, nil, nil, nil))
+
+	check(genesischeck.SectionRootHash, (&roothash.Genesis{Parameters: p.RootHash}).SanityCheck())
+	check(genesischeck.SectionStaking, p.Staking.SanityCheck())
+
+	if p.Scheduler.MinValidators < 1 {
+		check(genesischeck.SectionScheduler, fmt.Errorf("min_validators must be >= 1"))
+	}
+	if p.Scheduler.MaxValidators < p.Scheduler.MinValidators {
+		check(genesischeck.SectionScheduler, fmt.Errorf("max_validators %d is smaller than min_validators %d",
+			p.Scheduler.MaxValidators, p.Scheduler.MinValidators))
+	}
+	if p.Scheduler.MaxValidatorsPerEntity < 1 {
+		check(genesischeck.SectionScheduler, fmt.Errorf("max_validators_per_entity must be >= 1"))
+	}
+	check(genesischeck.SectionScheduler, (&scheduler.Genesis{Parameters: p.Scheduler}).SanityCheck(quantity.NewQuantity()))
+
+	check(genesischeck.SectionBeacon, (&beacon.Genesis{Parameters: p.Beacon}).SanityCheck())
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// WriteDump writes the effective network params as indented JSON with sorted
+// keys, exactly as they appear in the genesis document.
+func WriteDump(w io.Writer, p *NetworkParams) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var value interface{}
+	if err = decoder.Decode(&value); err != nil {
+		return err
+	}
+	if b, err = json.MarshalIndent(value, "", "  "); err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,115 @@
+package networkparams_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/networkparams"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+const fixturePath = "fixtures/network_params.json"
+
+func writeParams(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "networkparams")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "network_params.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	params, err := networkparams.Load(fixturePath)
+	require.NoError(t, err)
+	require.Equal(t, networkparams.ConsensusBackend, params.Consensus.Backend)
+	require.Equal(t, 5*time.Second, params.Consensus.Parameters.TimeoutCommit)
+	require.Equal(t, uint64(32*1024), params.Consensus.Parameters.MaxTxSize)
+	require.Equal(t, int64(600), params.EpochTime.Interval)
+	require.Equal(t, uint64(2), params.Registry.MaxNodeExpiration)
+	require.Equal(t, *quantity.NewFromUint64(100000000000), params.Staking.MinDelegationAmount)
+	require.Equal(t, 15, params.Scheduler.MinValidators)
+	require.Equal(t, 80, params.Scheduler.MaxValidators)
+}
+
+func TestLoadStrict(t *testing.T) {
+	b, err := ioutil.ReadFile(fixturePath)
+	require.NoError(t, err)
+	fixture := string(b)
+
+	// Unknown fields are rejected in every section.
+	path := writeParams(t, strings.Replace(fixture, `"interval": 600`, `"interval": 600, "intreval": 60`, 1))
+	_, err = networkparams.Load(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown field "intreval"`)
+	require.Contains(t, err.Error(), "network_params.json:19:")
+
+	path = writeParams(t, strings.Replace(fixture, `"beacon": {}`, `"beacon": {}, "halt_epoch": 1`, 1))
+	_, err = networkparams.Load(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown field "halt_epoch"`)
+
+	path = writeParams(t, strings.Replace(fixture, `"max_tx_size": 32768`, `"max_tx_size": "32kb"`, 1))
+	_, err = networkparams.Load(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "network_params.json:8:")
+
+	path = writeParams(t, strings.Replace(fixture, `"beacon": {}`, `"beacon_": {}`, 1))
+	_, err = networkparams.Load(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing section(s) [beacon]")
+}
+
+func TestValidate(t *testing.T) {
+	params, err := networkparams.Load(fixturePath)
+	require.NoError(t, err)
+
+	params.Consensus.Backend = "memory"
+	params.Consensus.Parameters.MaxBlockSize = 1024
+	params.EpochTime.Interval = 0
+	params.Registry.MaxNodeExpiration = 0
+	delete(params.Staking.Thresholds, staking.KindEntity)
+	params.Scheduler.MaxValidators = 10
+	params.Scheduler.MaxValidatorsPerEntity = 0
+
+	err = params.Validate()
+	var errs genesischeck.Errors
+	require.True(t, errors.As(err, &errs))
+	require.Equal(t, []string{
+		genesischeck.SectionConsensus,
+		genesischeck.SectionConsensus,
+		genesischeck.SectionEpochTime,
+		genesischeck.SectionRegistry,
+		genesischeck.SectionStaking,
+		genesischeck.SectionScheduler,
+		genesischeck.SectionScheduler,
+	}, errs.Sections())
+	require.Contains(t, errs[0].Error(), `backend must be "tendermint"`)
+	require.Contains(t, errs[1].Error(), "max_block_size 1024 is smaller than max_tx_size 32768")
+	require.Contains(t, errs[5].Error(), "max_validators 10 is smaller than min_validators 15")
+}
+
+func TestWriteDump(t *testing.T) {
+	params, err := networkparams.Load(fixturePath)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, networkparams.WriteDump(&buf, params))
+	require.Contains(t, buf.String(), `"timeout_commit": 5000000000`)
+
+	// The dump is a valid network params file itself.
+	reloaded, err := networkparams.Load(writeParams(t, buf.String()))
+	require.NoError(t, err)
+	require.Equal(t, params, reloaded)
+}
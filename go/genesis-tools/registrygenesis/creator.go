@@ -0,0 +1,89 @@
+package registrygenesis
+
+import (
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+var (
+	logger = logging.GetLogger("registrygenesis")
+)
+
+// FilterNodes returns the packages whose nodes follow the policy and every
+// violation of the packages whose nodes are excluded. Packages without a node
+// are never returned.
+func FilterNodes(packages []*stakinggenesis.EntityPackage, policy *stakinggenesis.NodePolicy) ([]*stakinggenesis.EntityPackage, []stakinggenesis.PolicyViolation) {
+	var included []*stakinggenesis.EntityPackage
+	var violations []stakinggenesis.PolicyViolation
+	for _, pkg := range packages {
+		if pkg.SignedNode == nil {
+			continue
+		}
+		if policy != nil {
+			if pkgViolations := policy.Check(pkg); len(pkgViolations) > 0 {
+				violations = append(violations, pkgViolations...)
+				continue
+			}
+		}
+		included = append(included, pkg)
+	}
+	return included, violations
+}
+
+// Create builds the registry genesis from entity packages. Keys must not be
+// shared between any of the packages, whether they are registered with their
+// nodes or not.
+func Create(options GenesisOptions) (*registry.Genesis, error) {
+	params, err := options.LoadConsensusParameters()
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make([]*stakinggenesis.EntityPackage, 0, len(options.Packages)+len(options.EntityOnlyPackages))
+	packages = append(packages, options.Packages...)
+	packages = append(packages, options.EntityOnlyPackages...)
+	if err = stakinggenesis.CheckDuplicateKeys(packages); err != nil {
+		return nil, err
+	}
+
+	genesis := &registry.Genesis{
+		Parameters:   *params,
+		Entities:     make([]*entity.SignedEntity, 0, len(options.Packages)+len(options.EntityOnlyPackages)),
+		Runtimes:     make([]*registry.SignedRuntime, 0),
+		Nodes:        make([]*node.MultiSignedNode, 0, len(options.Packages)),
+		NodeStatuses: make(map[signature.PublicKey]*registry.NodeStatus),
+	}
+
+	for _, pkg := range options.Packages {
+		genesis.Entities = append(genesis.Entities, pkg.SignedEntity)
+	}
+	for _, pkg := range options.EntityOnlyPackages {
+		genesis.Entities = append(genesis.Entities, pkg.SignedEntity)
+	}
+
+	policy := options.NodePolicy
+	if policy != nil {
+		// Node expirations are checked against the registry parameters of
+		// the genesis.
+		if policy, err = policy.WithRegistryParameters(*params); err != nil {
+			return nil, err
+		}
+	}
+	included, violations := FilterNodes(options.Packages, policy)
+	for _, violation := range violations {
+		logger.Warn("excluding node that violates the node policy",
+			"entity_name", violation.Package,
+			"rule", violation.Rule,
+			"reason", violation.Message,
+		)
+	}
+	for _, pkg := range included {
+		genesis.Nodes = append(genesis.Nodes, pkg.SignedNode)
+	}
+
+	return genesis, nil
+}
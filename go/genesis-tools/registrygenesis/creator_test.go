@@ -0,0 +1,162 @@
+package registrygenesis_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/registrygenesis"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// testPackage returns an entity package with a validator node whose keys are
+// derived from seed. nodeFn may modify the node before it is signed.
+func testPackage(t *testing.T, seed string, nodeFn func(*node.Node)) *stakinggenesis.EntityPackage {
+	entitySigner := memorySigner.NewTestSigner(seed)
+	nodeSigner := memorySigner.NewTestSigner(seed + "/node")
+	consensusSigner := memorySigner.NewTestSigner(seed + "/consensus")
+	p2pSigner := memorySigner.NewTestSigner(seed + "/p2p")
+	tlsSigner := memorySigner.NewTestSigner(seed + "/tls")
+
+	var consensusAddress node.ConsensusAddress
+	require.NoError(t, consensusAddress.UnmarshalText([]byte(p2pSigner.Public().String()+"@1.2.3.4:26656")))
+
+	n := &node.Node{
+		Versioned:  cbor.NewVersioned(node.LatestNodeDescriptorVersion),
+		ID:         nodeSigner.Public(),
+		EntityID:   entitySigner.Public(),
+		Expiration: 1,
+		TLS:        node.TLSInfo{PubKey: tlsSigner.Public()},
+		P2P:        node.P2PInfo{ID: p2pSigner.Public()},
+		Consensus: node.ConsensusInfo{
+			ID:        consensusSigner.Public(),
+			Addresses: []node.ConsensusAddress{consensusAddress},
+		},
+		Roles: node.RoleValidator,
+	}
+	if nodeFn != nil {
+		nodeFn(n)
+	}
+	signedNode, err := node.MultiSignNode(
+		[]signature.Signer{nodeSigner, consensusSigner, p2pSigner, tlsSigner},
+		registry.RegisterGenesisNodeSignatureContext,
+		n,
+	)
+	require.NoError(t, err)
+
+	ent := &entity.Entity{
+		Versioned: cbor.NewVersioned(entity.LatestEntityDescriptorVersion),
+		ID:        entitySigner.Public(),
+		Nodes:     []signature.PublicKey{n.ID},
+	}
+	signedEntity, err := entity.SignEntity(entitySigner, registry.RegisterGenesisEntitySignatureContext, ent)
+	require.NoError(t, err)
+
+	return &stakinggenesis.EntityPackage{
+		Name:         seed,
+		Entity:       ent,
+		SignedEntity: signedEntity,
+		Node:         n,
+		SignedNode:   signedNode,
+	}
+}
+
+func TestLoadRegistryConsensusParameters(t *testing.T) {
+	params, err := registrygenesis.LoadRegistryConsensusParameters("fixtures/registry_params.json")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, params.MaxNodeExpiration)
+	require.EqualValues(t, registry.DefaultGasCosts, params.GasCosts)
+
+	_, err = registrygenesis.LoadRegistryConsensusParameters("fixtures/missing.json")
+	require.Error(t, err)
+}
+
+func TestCreate(t *testing.T) {
+	valid := testPackage(t, "valid", nil)
+	compute := testPackage(t, "compute", func(n *node.Node) {
+		n.AddRoles(node.RoleComputeWorker)
+	})
+	entityOnly := testPackage(t, "entity-only", nil)
+
+	policy := stakinggenesis.DefaultNodePolicy()
+	genesis, err := registrygenesis.Create(registrygenesis.GenesisOptions{
+		Packages:                []*stakinggenesis.EntityPackage{valid, compute},
+		EntityOnlyPackages:      []*stakinggenesis.EntityPackage{entityOnly},
+		ConsensusParametersPath: "fixtures/registry_params.json",
+		NodePolicy:              policy,
+	})
+	require.NoError(t, err)
+
+	require.EqualValues(t, 2, genesis.Parameters.MaxNodeExpiration)
+	require.Equal(t, []*entity.SignedEntity{
+		valid.SignedEntity,
+		compute.SignedEntity,
+		entityOnly.SignedEntity,
+	}, genesis.Entities)
+	require.Equal(t, []*node.MultiSignedNode{valid.SignedNode}, genesis.Nodes)
+	require.Empty(t, genesis.Runtimes)
+
+	// Without a policy every node is registered.
+	genesis, err = registrygenesis.Create(registrygenesis.GenesisOptions{
+		Packages: []*stakinggenesis.EntityPackage{valid, compute},
+		ConsensusParametersLoader: func() registry.ConsensusParameters {
+			return registry.ConsensusParameters{MaxNodeExpiration: 5}
+		},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 5, genesis.Parameters.MaxNodeExpiration)
+	require.Equal(t, []*node.MultiSignedNode{valid.SignedNode, compute.SignedNode}, genesis.Nodes)
+
+	// The node policy cannot disagree with the registry parameters.
+	maxNodeExpiration := uint64(5)
+	policy.MaxNodeExpiration = &maxNodeExpiration
+	_, err = registrygenesis.Create(registrygenesis.GenesisOptions{
+		Packages:                []*stakinggenesis.EntityPackage{valid},
+		ConsensusParametersPath: "fixtures/registry_params.json",
+		NodePolicy:              policy,
+	})
+	require.EqualError(t, err, "node policy max_node_expiration 5 differs from the registry max_node_expiration 2")
+
+	// Keys cannot be shared between the packages and the entity only
+	// packages.
+	duplicate := testPackage(t, "valid", nil)
+	duplicate.Name = "duplicate"
+	_, err = registrygenesis.Create(registrygenesis.GenesisOptions{
+		Packages:                []*stakinggenesis.EntityPackage{valid},
+		EntityOnlyPackages:      []*stakinggenesis.EntityPackage{duplicate},
+		ConsensusParametersPath: "fixtures/registry_params.json",
+	})
+	var dupErr *stakinggenesis.DuplicateKeysError
+	require.True(t, errors.As(err, &dupErr), "unexpected error: %v", err)
+	require.Len(t, dupErr.Conflicts, 5)
+	for _, conflict := range dupErr.Conflicts {
+		require.Equal(t, []string{"duplicate", "valid"}, conflict.Packages)
+	}
+}
+
+func TestFilterNodes(t *testing.T) {
+	valid := testPackage(t, "valid", nil)
+	expiration := testPackage(t, "expiration", func(n *node.Node) {
+		n.Expiration = 3
+	})
+	noNode := testPackage(t, "no-node", nil)
+	noNode.Node, noNode.SignedNode = nil, nil
+
+	policy, err := stakinggenesis.DefaultNodePolicy().WithRegistryParameters(registry.ConsensusParameters{MaxNodeExpiration: 2})
+	require.NoError(t, err)
+	included, violations := registrygenesis.FilterNodes(
+		[]*stakinggenesis.EntityPackage{valid, expiration, noNode},
+		policy,
+	)
+	require.Equal(t, []*stakinggenesis.EntityPackage{valid}, included)
+	require.Len(t, violations, 1)
+	require.Equal(t, "expiration", violations[0].Package)
+	require.Equal(t, stakinggenesis.RuleMaxNodeExpiration, violations[0].Rule)
+}
@@ -0,0 +1,50 @@
+package registrygenesis
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// GenesisOptions options for the registry genesis document.
+type GenesisOptions struct {
+	// Packages are registered with their entity and node descriptors.
+	Packages []*stakinggenesis.EntityPackage
+	// EntityOnlyPackages are registered without their node descriptors.
+	EntityOnlyPackages []*stakinggenesis.EntityPackage
+
+	ConsensusParametersPath   string
+	ConsensusParametersLoader func() registry.ConsensusParameters
+
+	// NodePolicy excludes the nodes of packages that violate it. Nil
+	// includes every node. Its max node expiration must match the registry
+	// parameters.
+	NodePolicy *stakinggenesis.NodePolicy
+}
+
+// LoadConsensusParameters returns the registry parameters from the loader if
+// set, otherwise from ConsensusParametersPath.
+func (g GenesisOptions) LoadConsensusParameters() (*registry.ConsensusParameters, error) {
+	if g.ConsensusParametersLoader != nil {
+		params := g.ConsensusParametersLoader()
+		return &params, nil
+	}
+	return LoadRegistryConsensusParameters(g.ConsensusParametersPath)
+}
+
+// LoadRegistryConsensusParameters - Load Registry Consensus Params from a file
+func LoadRegistryConsensusParameters(path string) (*registry.ConsensusParameters, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var params registry.ConsensusParameters
+	err = json.Unmarshal(b, &params)
+	if err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
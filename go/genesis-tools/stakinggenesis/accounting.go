@@ -1,8 +1,12 @@
 package stakinggenesis
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
+	"sort"
 
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/quantity"
 	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
 )
@@ -13,35 +17,118 @@ import (
 type AccountingGenesis struct {
 	ledger               StakingAccounts
 	delegations          StakingDelegations
+	debondingDelegations StakingDebondingDelegations
+	// totalAllocatedTokens are the base units held by the ledger, the
+	// remainder of the total supply is the common pool.
 	totalAllocatedTokens *quantity.Quantity
-	totalSupply          *quantity.Quantity
-	commissionRateMax    *quantity.Quantity
-	commissionRateMin    *quantity.Quantity
-	commissionRate       *quantity.Quantity
-	precision            uint64
+	// totalSupply and lastBlockFees are in base units.
+	totalSupply       *quantity.Quantity
+	lastBlockFees     *quantity.Quantity
+	commissionRateMax *quantity.Quantity
+	commissionRateMin *quantity.Quantity
+	commissionRate    *quantity.Quantity
+	precision         *quantity.Quantity
+	// defaultSchedules are the accounts whose commission schedule was set
+	// by their first delegation, it is cleared with their last one.
+	defaultSchedules map[staking.Address]bool
 }
 
-func NewAccountingGenesis(precision, totalSupply, commissionRateMax, commissionRateMin, commissionRate uint64) *AccountingGenesis {
+// Precision returns the number of base units in a whole token,
+// 10^tokenValueExponent, computed exactly.
+func Precision(tokenValueExponent uint8) (*quantity.Quantity, error) {
+	if tokenValueExponent > MaxTokenValueExponent {
+		return nil, fmt.Errorf("token value exponent %d exceeds the maximum of %d", tokenValueExponent, MaxTokenValueExponent)
+	}
+
+	var precision big.Int
+	precision.Exp(big.NewInt(10), big.NewInt(int64(tokenValueExponent)), nil)
+
+	q := quantity.NewQuantity()
+	if err := q.FromBigInt(&precision); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// NewAccountingGenesis creates an AccountingGenesis. Token amounts are whole
+// tokens, precision is the number of base units in a token.
+func NewAccountingGenesis(precision *quantity.Quantity, totalSupply, commissionRateMax, commissionRateMin, commissionRate uint64) *AccountingGenesis {
+	preciseTotalSupply := precision.Clone()
+	// Multiplying non-negative quantities cannot fail.
+	_ = preciseTotalSupply.Mul(quantity.NewFromUint64(totalSupply))
+
 	return &AccountingGenesis{
 		ledger:               make(StakingAccounts),
 		delegations:          make(StakingDelegations),
+		debondingDelegations: make(StakingDebondingDelegations),
 		totalAllocatedTokens: quantity.NewFromUint64(0),
-		precision:            precision,
-		totalSupply:          quantity.NewFromUint64(totalSupply),
+		precision:            precision.Clone(),
+		totalSupply:          preciseTotalSupply,
+		lastBlockFees:        quantity.NewFromUint64(0),
 		commissionRateMax:    quantity.NewFromUint64(commissionRateMax),
 		commissionRateMin:    quantity.NewFromUint64(commissionRateMin),
 		commissionRate:       quantity.NewFromUint64(commissionRate),
+		defaultSchedules:     make(map[staking.Address]bool),
 	}
 }
 
-func (a *AccountingGenesis) preciseTokens(q *quantity.Quantity) *quantity.Quantity {
-	preciseTokens := quantity.NewFromUint64(a.precision)
-	preciseTokens.Mul(q)
-	return preciseTokens
+// NewAccountingGenesisFromGenesis creates an AccountingGenesis seeded with an
+// existing staking genesis, like the staking section of a state dump. The
+// ledger, including shares, debonding delegations and commission schedules,
+// is kept intact and accounts and delegations are added on top of it. The
+// precision is derived from the token value exponent of the base genesis, the
+// commission rates apply to accounts that get their first delegation.
+func NewAccountingGenesisFromGenesis(base *staking.Genesis, commissionRateMax, commissionRateMin, commissionRate uint64) (*AccountingGenesis, error) {
+	precision, err := Precision(base.TokenValueExponent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deep copy the base so that it is not modified.
+	var seed staking.Genesis
+	if err = cbor.Unmarshal(cbor.Marshal(base), &seed); err != nil {
+		return nil, fmt.Errorf("failed to copy the base genesis: %w", err)
+	}
+
+	a := NewAccountingGenesis(precision, 0, commissionRateMax, commissionRateMin, commissionRate)
+	a.totalSupply = seed.TotalSupply.Clone()
+	a.lastBlockFees = seed.LastBlockFees.Clone()
+	if seed.Ledger != nil {
+		a.ledger = seed.Ledger
+	}
+	if seed.Delegations != nil {
+		a.delegations = seed.Delegations
+	}
+	if seed.DebondingDelegations != nil {
+		a.debondingDelegations = seed.DebondingDelegations
+	}
+
+	a.totalAllocatedTokens = seed.TotalSupply.Clone()
+	if err = a.totalAllocatedTokens.Sub(&seed.CommonPool); err != nil {
+		return nil, fmt.Errorf("base genesis common pool %s exceeds the total supply %s", &seed.CommonPool, &seed.TotalSupply)
+	}
+	if err = a.totalAllocatedTokens.Sub(&seed.LastBlockFees); err != nil {
+		return nil, fmt.Errorf("base genesis common pool and last block fees exceed the total supply %s", &seed.TotalSupply)
+	}
+
+	if err = a.CheckInvariants(); err != nil {
+		return nil, fmt.Errorf("base genesis: %w", err)
+	}
+	return a, nil
 }
 
-func (a *AccountingGenesis) preciseTokensFromUint64(tokens uint64) *quantity.Quantity {
-	return a.preciseTokens(quantity.NewFromUint64(tokens))
+// HasAccount returns true iff the ledger has an account for the address.
+func (a *AccountingGenesis) HasAccount(address staking.Address) bool {
+	return a.accountExists(address)
+}
+
+// preciseTokens converts whole tokens to base units.
+func (a *AccountingGenesis) preciseTokens(q *quantity.Quantity) (*quantity.Quantity, error) {
+	preciseTokens := a.precision.Clone()
+	if err := preciseTokens.Mul(q); err != nil {
+		return nil, err
+	}
+	return preciseTokens, nil
 }
 
 // AddAccount initializes an account on the AccountingGenesis
@@ -50,7 +137,10 @@ func (a *AccountingGenesis) AddAccount(address staking.Address, tokenBalance *qu
 		return fmt.Errorf(`duplicate account found for "%s"`, address)
 	}
 
-	preciseTokenBalance := a.preciseTokens(tokenBalance)
+	preciseTokenBalance, err := a.preciseTokens(tokenBalance)
+	if err != nil {
+		return err
+	}
 
 	a.ledger[address] = &staking.Account{
 		General: staking.GeneralAccount{
@@ -69,9 +159,7 @@ func (a *AccountingGenesis) AddAccount(address staking.Address, tokenBalance *qu
 		},
 	}
 
-	a.totalAllocatedTokens.Add(preciseTokenBalance)
-
-	return nil
+	return a.totalAllocatedTokens.Add(preciseTokenBalance)
 }
 
 func (a *AccountingGenesis) accountExists(address staking.Address) bool {
@@ -79,8 +167,22 @@ func (a *AccountingGenesis) accountExists(address staking.Address) bool {
 	return ok
 }
 
+// AddDelegation escrows whole tokens of the general balance of from with to.
+// Every delegation is expected to be added once.
 func (a *AccountingGenesis) AddDelegation(from staking.Address, to staking.Address, amount *quantity.Quantity) error {
-	preciseAmount := a.preciseTokens(amount)
+	if _, ok := a.delegations[to][from]; ok {
+		return fmt.Errorf(`duplicate delegation from "%s" to "%s"`, from, to)
+	}
+	return a.Delegate(from, to, amount)
+}
+
+// Delegate escrows whole tokens of the general balance of from with to,
+// adding to an existing delegation.
+func (a *AccountingGenesis) Delegate(from staking.Address, to staking.Address, amount *quantity.Quantity) error {
+	preciseAmount, err := a.preciseTokens(amount)
+	if err != nil {
+		return err
+	}
 
 	// Ensure that the accounts exist
 	if !a.accountExists(from) {
@@ -89,37 +191,38 @@ func (a *AccountingGenesis) AddDelegation(from staking.Address, to staking.Addre
 	if !a.accountExists(to) {
 		return fmt.Errorf(`cannot delegate. account "%s" does not exist`, to)
 	}
-
-	if _, ok := a.delegations[to]; !ok {
-		a.delegations[to] = make(map[staking.Address]*staking.Delegation)
-	}
-
-	// Check that this isn't a duplicate delegation
-	if _, ok := a.delegations[to][from]; ok {
-		return fmt.Errorf(`duplicate delegation from "%s" to "%s"`, from, to)
+	active := &a.ledger[to].Escrow.Active
+	if shares, err := sharesForBaseUnits(active, preciseAmount); err != nil {
+		return fmt.Errorf(`cannot delegate. escrow of "%s": %w`, to, err)
+	} else if shares.IsZero() {
+		return fmt.Errorf(`cannot delegate. %s base units buy no shares of the escrow of "%s"`, preciseAmount, to)
 	}
 
-	// Subtract from the "from" account to escrow into the "to" acocunt
-	err := a.ledger[from].General.Balance.Sub(preciseAmount)
-	if err != nil {
-		return err
+	// Escrow from the "from" account into the "to" account at the rate of
+	// its share pool
+	var shares quantity.Quantity
+	if err = active.Deposit(&shares, &a.ledger[from].General.Balance, preciseAmount); err != nil {
+		return fmt.Errorf(`cannot delegate %s base units from "%s" with a balance of %s: %w`,
+			preciseAmount, from, &a.ledger[from].General.Balance, err)
 	}
 
-	a.delegations[to][from] = &staking.Delegation{
-		Shares: *preciseAmount.Clone(),
+	if _, ok := a.delegations[to]; !ok {
+		a.delegations[to] = make(map[staking.Address]*staking.Delegation)
 	}
-
-	err = a.ledger[to].Escrow.Active.Balance.Add(preciseAmount)
-	if err != nil {
-		return err
+	delegation, ok := a.delegations[to][from]
+	if !ok {
+		delegation = &staking.Delegation{Shares: *quantity.NewFromUint64(0)}
+		a.delegations[to][from] = delegation
 	}
-	err = a.ledger[to].Escrow.Active.TotalShares.Add(preciseAmount)
-	if err != nil {
+	if err = delegation.Shares.Add(&shares); err != nil {
 		return err
 	}
 
 	// Ensure the commission schedule is set since this account is getting
-	// delegations.
+	// delegations. An existing schedule, like one of a base genesis, is kept.
+	if len(a.ledger[to].Escrow.CommissionSchedule.Rates) > 0 {
+		return nil
+	}
 	a.ledger[to].Escrow.CommissionSchedule.Rates = []staking.CommissionRateStep{
 		{
 			Start: 0,
@@ -134,20 +237,338 @@ func (a *AccountingGenesis) AddDelegation(from staking.Address, to staking.Addre
 			RateMax: *a.commissionRateMax.Clone(),
 		},
 	}
+	a.defaultSchedules[to] = true
 
 	return nil
 }
 
-func (a *AccountingGenesis) GetPartialGenesis() staking.Genesis {
-	preciseTotalSupply := a.preciseTokens(a.totalSupply)
+// clearCommissionSchedule clears the commission schedule set by the first
+// delegation to an account once it has no delegations left. Other
+// schedules, like ones of a base genesis, are kept.
+func (a *AccountingGenesis) clearCommissionSchedule(address staking.Address) {
+	if len(a.delegations[address]) > 0 || !a.defaultSchedules[address] {
+		return
+	}
+	a.ledger[address].Escrow.CommissionSchedule = staking.CommissionSchedule{}
+	delete(a.defaultSchedules, address)
+}
 
-	preciseCommonPool := preciseTotalSupply.Clone()
-	preciseCommonPool.Sub(a.totalAllocatedTokens)
+// sharesForBaseUnits returns the shares of a pool worth base units, rounded
+// down like the share pools of oasis-core do:
+//
+//	shares = base_units * total_shares / balance
+//
+// Pools without shares issue them 1:1.
+func sharesForBaseUnits(pool *staking.SharePool, baseUnits *quantity.Quantity) (*quantity.Quantity, error) {
+	if pool.TotalShares.IsZero() {
+		return baseUnits.Clone(), nil
+	}
+	if pool.Balance.IsZero() {
+		return nil, fmt.Errorf("%s shares have no balance", &pool.TotalShares)
+	}
 
-	return staking.Genesis{
-		Ledger:      a.ledger,
-		Delegations: a.delegations,
-		TotalSupply: *preciseTotalSupply,
-		CommonPool:  *preciseCommonPool,
+	shares := baseUnits.Clone()
+	if err := shares.Mul(&pool.TotalShares); err != nil {
+		return nil, err
+	}
+	if err := shares.Quo(&pool.Balance); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// RemoveDelegation returns whole tokens escrowed by from with to to the
+// general balance of from. A nil amount removes the whole delegation. Tokens
+// are exchanged for shares at the rate of the share pool, both rounded down, so
+// the delegator may get back less than the amount from non-1:1 pools.
+func (a *AccountingGenesis) RemoveDelegation(from staking.Address, to staking.Address, amount *quantity.Quantity) error {
+	delegation, ok := a.delegations[to][from]
+	if !ok {
+		return fmt.Errorf(`cannot undelegate. no delegation from "%s" to "%s"`, from, to)
+	}
+
+	active := &a.ledger[to].Escrow.Active
+	shares := delegation.Shares.Clone()
+	if amount != nil {
+		preciseAmount, err := a.preciseTokens(amount)
+		if err != nil {
+			return err
+		}
+		if shares, err = sharesForBaseUnits(active, preciseAmount); err != nil {
+			return fmt.Errorf(`cannot undelegate. escrow of "%s": %w`, to, err)
+		}
+		if shares.Cmp(&delegation.Shares) > 0 {
+			return fmt.Errorf(`cannot undelegate %s base units (%s shares) from "%s" to "%s" with %s shares`,
+				preciseAmount, shares, from, to, &delegation.Shares)
+		}
+		if shares.IsZero() {
+			return fmt.Errorf(`cannot undelegate. %s base units are less than a share of the escrow of "%s"`, preciseAmount, to)
+		}
+	}
+
+	if err := active.Withdraw(&a.ledger[from].General.Balance, &delegation.Shares, shares); err != nil {
+		return err
+	}
+	if delegation.Shares.IsZero() {
+		delete(a.delegations[to], from)
+		if len(a.delegations[to]) == 0 {
+			delete(a.delegations, to)
+		}
+	}
+	a.clearCommissionSchedule(to)
+	return nil
+}
+
+// removeDebondingDelegations returns the debonding delegations from from to
+// to to the general balance of from, at the rate of the debonding pool.
+func (a *AccountingGenesis) removeDebondingDelegations(from staking.Address, to staking.Address) error {
+	for _, delegation := range a.debondingDelegations[to][from] {
+		shares := delegation.Shares.Clone()
+		err := a.ledger[to].Escrow.Debonding.Withdraw(&a.ledger[from].General.Balance, &delegation.Shares, shares)
+		if err != nil {
+			return err
+		}
+	}
+	delete(a.debondingDelegations[to], from)
+	if len(a.debondingDelegations[to]) == 0 {
+		delete(a.debondingDelegations, to)
+	}
+	return nil
+}
+
+// TransferBalance moves whole tokens between the general balances of two
+// accounts.
+func (a *AccountingGenesis) TransferBalance(from staking.Address, to staking.Address, amount *quantity.Quantity) error {
+	preciseAmount, err := a.preciseTokens(amount)
+	if err != nil {
+		return err
+	}
+	if !a.accountExists(from) {
+		return fmt.Errorf(`cannot transfer. account "%s" does not exist`, from)
+	}
+	if !a.accountExists(to) {
+		return fmt.Errorf(`cannot transfer. account "%s" does not exist`, to)
+	}
+
+	if err = a.ledger[from].General.Balance.Sub(preciseAmount); err != nil {
+		return fmt.Errorf(`cannot transfer %s base units from "%s" with a balance of %s`,
+			preciseAmount, from, &a.ledger[from].General.Balance)
+	}
+	return a.ledger[to].General.Balance.Add(preciseAmount)
+}
+
+// SetBalance sets the general balance of an account to whole tokens. The
+// difference is taken from, or returned to, the common pool.
+func (a *AccountingGenesis) SetBalance(address staking.Address, tokenBalance *quantity.Quantity) error {
+	preciseTokenBalance, err := a.preciseTokens(tokenBalance)
+	if err != nil {
+		return err
+	}
+	if !a.accountExists(address) {
+		return fmt.Errorf(`cannot set balance. account "%s" does not exist`, address)
+	}
+
+	balance := &a.ledger[address].General.Balance
+	if err = a.totalAllocatedTokens.Sub(balance); err != nil {
+		return err
+	}
+	if err = a.totalAllocatedTokens.Add(preciseTokenBalance); err != nil {
+		return err
+	}
+	*balance = *preciseTokenBalance
+	return nil
+}
+
+// AddBalance adds whole tokens from the common pool to the general balance
+// of an account.
+func (a *AccountingGenesis) AddBalance(address staking.Address, amount *quantity.Quantity) error {
+	preciseAmount, err := a.preciseTokens(amount)
+	if err != nil {
+		return err
+	}
+	if !a.accountExists(address) {
+		return fmt.Errorf(`cannot add balance. account "%s" does not exist`, address)
+	}
+
+	if err = a.ledger[address].General.Balance.Add(preciseAmount); err != nil {
+		return err
+	}
+	return a.totalAllocatedTokens.Add(preciseAmount)
+}
+
+// RemoveAccount removes an account from the ledger. Its inbound
+// delegations and debonding delegations are refunded to the general balances
+// of their delegators. Its own balances, including what it delegated to other
+// accounts, are returned to the common pool.
+func (a *AccountingGenesis) RemoveAccount(address staking.Address) error {
+	if !a.accountExists(address) {
+		return fmt.Errorf(`cannot remove account. account "%s" does not exist`, address)
+	}
+
+	// Refund in address order so that the rounding of non-1:1 pools does not
+	// depend on map iteration. Self delegations are inbound.
+	inbound, outbound := a.delegators(address)
+	for _, from := range inbound {
+		if err := a.RemoveDelegation(from, address, nil); err != nil {
+			return err
+		}
+	}
+	for _, to := range outbound {
+		if err := a.RemoveDelegation(address, to, nil); err != nil {
+			return err
+		}
+	}
+	inbound, outbound = a.debondingDelegators(address)
+	for _, from := range inbound {
+		if err := a.removeDebondingDelegations(from, address); err != nil {
+			return err
+		}
+	}
+	for _, to := range outbound {
+		if err := a.removeDebondingDelegations(address, to); err != nil {
+			return err
+		}
+	}
+
+	account := a.ledger[address]
+	for _, balance := range []*quantity.Quantity{
+		&account.General.Balance,
+		&account.Escrow.Active.Balance,
+		&account.Escrow.Debonding.Balance,
+	} {
+		if err := a.totalAllocatedTokens.Sub(balance); err != nil {
+			return err
+		}
+	}
+	delete(a.ledger, address)
+	delete(a.defaultSchedules, address)
+	return nil
+}
+
+// sortAddresses sorts addresses in ascending order.
+func sortAddresses(addresses []staking.Address) []staking.Address {
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i][:], addresses[j][:]) < 0
+	})
+	return addresses
+}
+
+// delegators returns the accounts that delegate to an account, and the
+// accounts that it delegates to, in address order.
+func (a *AccountingGenesis) delegators(address staking.Address) (inbound, outbound []staking.Address) {
+	for to, delegations := range a.delegations {
+		for from := range delegations {
+			switch {
+			case to.Equal(address):
+				inbound = append(inbound, from)
+			case from.Equal(address):
+				outbound = append(outbound, to)
+			}
+		}
+	}
+	return sortAddresses(inbound), sortAddresses(outbound)
+}
+
+// debondingDelegators is delegators for debonding delegations.
+func (a *AccountingGenesis) debondingDelegators(address staking.Address) (inbound, outbound []staking.Address) {
+	for to, delegations := range a.debondingDelegations {
+		for from := range delegations {
+			switch {
+			case to.Equal(address):
+				inbound = append(inbound, from)
+			case from.Equal(address):
+				outbound = append(outbound, to)
+			}
+		}
+	}
+	return sortAddresses(inbound), sortAddresses(outbound)
+}
+
+// commonPool returns the base units of the total supply that are neither in
+// the ledger nor last block fees.
+func (a *AccountingGenesis) commonPool() (*quantity.Quantity, error) {
+	commonPool := a.totalSupply.Clone()
+	if err := commonPool.Sub(a.totalAllocatedTokens); err != nil {
+		return nil, fmt.Errorf("allocated tokens %s exceed the total supply %s", a.totalAllocatedTokens, a.totalSupply)
+	}
+	if err := commonPool.Sub(a.lastBlockFees); err != nil {
+		return nil, fmt.Errorf("allocated tokens %s and last block fees %s exceed the total supply %s",
+			a.totalAllocatedTokens, a.lastBlockFees, a.totalSupply)
+	}
+	return commonPool, nil
+}
+
+// CheckInvariants checks that the balances of the ledger add up to the
+// allocated tokens, and that the shares of the delegations and debonding
+// delegations add up to the share pools of every account.
+func (a *AccountingGenesis) CheckInvariants() error {
+	if _, err := a.commonPool(); err != nil {
+		return err
+	}
+
+	var total quantity.Quantity
+	for _, account := range a.ledger {
+		for _, balance := range []*quantity.Quantity{
+			&account.General.Balance,
+			&account.Escrow.Active.Balance,
+			&account.Escrow.Debonding.Balance,
+		} {
+			if err := total.Add(balance); err != nil {
+				return err
+			}
+		}
+	}
+	if total.Cmp(a.totalAllocatedTokens) != 0 {
+		return fmt.Errorf("balances in the ledger %s do not add up to the allocated tokens %s", &total, a.totalAllocatedTokens)
+	}
+
+	for address, delegations := range a.delegations {
+		account, ok := a.ledger[address]
+		if !ok {
+			return fmt.Errorf(`delegations to nonexistent account "%s"`, address)
+		}
+		if err := staking.SanityCheckDelegations(address, account, delegations); err != nil {
+			return err
+		}
+	}
+	for address, delegations := range a.debondingDelegations {
+		account, ok := a.ledger[address]
+		if !ok {
+			return fmt.Errorf(`debonding delegations to nonexistent account "%s"`, address)
+		}
+		if err := staking.SanityCheckDebondingDelegations(address, account, delegations); err != nil {
+			return err
+		}
+	}
+	for address, account := range a.ledger {
+		err := staking.SanityCheckAccountShares(address, account, a.delegations[address], a.debondingDelegations[address])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPartialGenesis returns the ledger and delegations, the remainder of the
+// total supply is put in the common pool. The invariants are checked first.
+func (a *AccountingGenesis) GetPartialGenesis() (*staking.Genesis, error) {
+	if err := a.CheckInvariants(); err != nil {
+		return nil, err
+	}
+	commonPool, err := a.commonPool()
+	if err != nil {
+		return nil, err
+	}
+
+	genesis := &staking.Genesis{
+		Ledger:        a.ledger,
+		Delegations:   a.delegations,
+		TotalSupply:   *a.totalSupply.Clone(),
+		CommonPool:    *commonPool,
+		LastBlockFees: *a.lastBlockFees.Clone(),
+	}
+	if len(a.debondingDelegations) > 0 {
+		genesis.DebondingDelegations = a.debondingDelegations
 	}
+	return genesis, nil
 }
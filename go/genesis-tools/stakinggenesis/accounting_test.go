@@ -1,6 +1,8 @@
 package stakinggenesis_test
 
 import (
+	"errors"
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -39,13 +41,18 @@ func requireQuantityEqual(t *testing.T, actual quantity.Quantity, expected uint6
 }
 
 func baseAccountingGenesis() *stakinggenesis.AccountingGenesis {
-	return stakinggenesis.NewAccountingGenesis(1_000_000_000, 10_000_000_000, 10000, 0, 5000)
+	precision, err := stakinggenesis.Precision(9)
+	if err != nil {
+		panic(err)
+	}
+	return stakinggenesis.NewAccountingGenesis(precision, 10_000_000_000, 10000, 0, 5000)
 }
 
 func TestLoadAccountingGenesis(t *testing.T) {
 	genesis := baseAccountingGenesis()
 
-	partial := genesis.GetPartialGenesis()
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
 
 	require.Equal(t, partial.TotalSupply, *quantity.NewFromUint64(10_000_000_000_000_000_000))
 	require.Equal(t, partial.CommonPool, *quantity.NewFromUint64(10_000_000_000_000_000_000))
@@ -61,7 +68,8 @@ func TestAddAccountsToAccountingGenesis(t *testing.T) {
 	genesis.AddAccount(testAddress1, quantity.NewFromUint64(1_000_000_000))
 	genesis.AddAccount(testAddress2, quantity.NewFromUint64(3_000_000_000))
 
-	partial := genesis.GetPartialGenesis()
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
 
 	require.Equal(t, partial.TotalSupply, *quantity.NewFromUint64(10_000_000_000_000_000_000))
 	require.Equal(t, partial.CommonPool, *quantity.NewFromUint64(6_000_000_000_000_000_000))
@@ -89,7 +97,8 @@ func TestAddAccountsAndDelegationToAccountingGenesis(t *testing.T) {
 	genesis.AddDelegation(testAddress2, testAddress3, quantity.NewFromUint64(200_000_000))
 	genesis.AddDelegation(testAddress2, testAddress4, quantity.NewFromUint64(200_000_000))
 
-	partial := genesis.GetPartialGenesis()
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
 
 	// Check balances
 	requireQuantityEqual(t, partial.Ledger[testAddress1].General.Balance, 600_000_000_000_000_000)
@@ -130,5 +139,396 @@ func TestDelegateTooMuchAccountingGenesis(t *testing.T) {
 	genesis.AddAccount(testAddress2, quantity.NewFromUint64(0))
 
 	err := genesis.AddDelegation(testAddress1, testAddress2, quantity.NewFromUint64(1_000_000_001))
-	require.Error(t, err, "insufficient balance")
+	require.True(t, errors.Is(err, quantity.ErrInsufficientBalance), "unexpected error: %v", err)
+	require.Contains(t, err.Error(), "with a balance of 1000000000000000000")
+}
+
+func requireQuantityString(t *testing.T, actual quantity.Quantity, expected string) {
+	var q quantity.Quantity
+	require.NoError(t, q.UnmarshalText([]byte(expected)))
+	require.Zero(t, q.Cmp(&actual), "expected %s, got %s", expected, &actual)
+}
+
+func TestPrecision(t *testing.T) {
+	for _, tc := range []struct {
+		exponent uint8
+		expected string
+	}{
+		{0, "1"},
+		{1, "10"},
+		{9, "1000000000"},
+		{17, "100000000000000000"},
+		{stakinggenesis.MaxTokenValueExponent, "1000000000000000000"},
+	} {
+		precision, err := stakinggenesis.Precision(tc.exponent)
+		require.NoError(t, err)
+		requireQuantityString(t, *precision, tc.expected)
+	}
+
+	_, err := stakinggenesis.Precision(stakinggenesis.MaxTokenValueExponent + 1)
+	require.Error(t, err)
+	_, err = stakinggenesis.Precision(255)
+	require.Error(t, err)
+}
+
+func TestAccountingGenesisOverflow(t *testing.T) {
+	precision, err := stakinggenesis.Precision(stakinggenesis.MaxTokenValueExponent)
+	require.NoError(t, err)
+
+	// Every product below exceeds the range of an uint64.
+	genesis := stakinggenesis.NewAccountingGenesis(precision, math.MaxUint64, 10000, 0, 5000)
+
+	testAddress1 := randomStakingAddress()
+	testAddress2 := randomStakingAddress()
+	require.NoError(t, genesis.AddAccount(testAddress1, quantity.NewFromUint64(math.MaxUint64-1)))
+	require.NoError(t, genesis.AddAccount(testAddress2, quantity.NewFromUint64(1)))
+	require.NoError(t, genesis.AddDelegation(testAddress1, testAddress2, quantity.NewFromUint64(10_000_000_000)))
+
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	requireQuantityString(t, partial.TotalSupply, "18446744073709551615000000000000000000")
+	requireQuantityString(t, partial.CommonPool, "0")
+	requireQuantityString(t, partial.Ledger[testAddress1].General.Balance, "18446744063709551614000000000000000000")
+	requireQuantityString(t, partial.Ledger[testAddress2].Escrow.Active.Balance, "10000000000000000000000000000")
+	requireQuantityString(t, partial.Delegations[testAddress2][testAddress1].Shares, "10000000000000000000000000000")
+}
+
+func TestAccountingGenesisOverAllocated(t *testing.T) {
+	precision, err := stakinggenesis.Precision(9)
+	require.NoError(t, err)
+	genesis := stakinggenesis.NewAccountingGenesis(precision, 100, 10000, 0, 5000)
+
+	require.NoError(t, genesis.AddAccount(randomStakingAddress(), quantity.NewFromUint64(101)))
+	_, err = genesis.GetPartialGenesis()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exceed the total supply")
+}
+
+// dumpedGenesis returns a staking genesis like one of a state dump, with a
+// rewarded escrow, a debonding delegation and a custom commission schedule.
+func dumpedGenesis(validator, delegator staking.Address) *staking.Genesis {
+	q := func(v uint64) quantity.Quantity {
+		return *quantity.NewFromUint64(v)
+	}
+
+	return &staking.Genesis{
+		TokenSymbol:        "ROSE",
+		TokenValueExponent: 9,
+		TotalSupply:        q(10_000),
+		CommonPool:         q(8_000),
+		LastBlockFees:      q(10),
+		Ledger: map[staking.Address]*staking.Account{
+			validator: {
+				General: staking.GeneralAccount{Balance: q(390), Nonce: 7},
+				Escrow: staking.EscrowAccount{
+					Active:    staking.SharePool{Balance: q(1_200), TotalShares: q(1_000)},
+					Debonding: staking.SharePool{Balance: q(100), TotalShares: q(100)},
+					CommissionSchedule: staking.CommissionSchedule{
+						Rates: []staking.CommissionRateStep{{Start: 0, Rate: q(1_234)}},
+					},
+				},
+			},
+			delegator: {
+				General: staking.GeneralAccount{Balance: q(300)},
+			},
+		},
+		Delegations: map[staking.Address]map[staking.Address]*staking.Delegation{
+			validator: {
+				validator: {Shares: q(600)},
+				delegator: {Shares: q(400)},
+			},
+		},
+		DebondingDelegations: map[staking.Address]map[staking.Address][]*staking.DebondingDelegation{
+			validator: {
+				delegator: {{Shares: q(100), DebondEndTime: 42}},
+			},
+		},
+	}
+}
+
+func TestAccountingGenesisFromGenesis(t *testing.T) {
+	validator := randomStakingAddress()
+	delegator := randomStakingAddress()
+	base := dumpedGenesis(validator, delegator)
+
+	genesis, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.NoError(t, err)
+	require.True(t, genesis.HasAccount(validator))
+
+	// The dump is kept intact.
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	require.Equal(t, base.Ledger, partial.Ledger)
+	require.Equal(t, base.Delegations, partial.Delegations)
+	require.Equal(t, base.DebondingDelegations, partial.DebondingDelegations)
+	require.Equal(t, base.CommonPool, partial.CommonPool)
+	require.Equal(t, base.LastBlockFees, partial.LastBlockFees)
+
+	// New accounts and delegations are layered on top, in whole tokens.
+	newEntity := randomStakingAddress()
+	require.NoError(t, genesis.AddAccount(newEntity, quantity.NewFromUint64(0)))
+	require.Error(t, genesis.AddAccount(delegator, quantity.NewFromUint64(1)))
+	require.Error(t, genesis.AddDelegation(newEntity, validator, quantity.NewFromUint64(1)))
+
+	// The base genesis is not modified.
+	require.Len(t, base.Ledger, 2)
+}
+
+func TestAccountingGenesisFromGenesisDelta(t *testing.T) {
+	validator := randomStakingAddress()
+	delegator := randomStakingAddress()
+	base := dumpedGenesis(validator, delegator)
+	base.TokenValueExponent = 0
+
+	genesis, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.NoError(t, err)
+
+	newEntity := randomStakingAddress()
+	require.NoError(t, genesis.AddAccount(newEntity, quantity.NewFromUint64(1_000)))
+	require.NoError(t, genesis.AddDelegation(newEntity, newEntity, quantity.NewFromUint64(900)))
+	require.NoError(t, genesis.AddDelegation(delegator, newEntity, quantity.NewFromUint64(100)))
+
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	requireQuantityEqual(t, partial.CommonPool, 7_000)
+	requireQuantityEqual(t, partial.TotalSupply, 10_000)
+	requireQuantityEqual(t, partial.Ledger[delegator].General.Balance, 200)
+	requireQuantityEqual(t, partial.Ledger[newEntity].Escrow.Active.Balance, 1_000)
+	requireQuantityEqual(t, partial.Ledger[newEntity].Escrow.CommissionSchedule.Rates[0].Rate, 5000)
+	// Existing commission schedules are kept.
+	requireQuantityEqual(t, partial.Ledger[validator].Escrow.CommissionSchedule.Rates[0].Rate, 1_234)
+}
+
+func TestAccountingGenesisFromGenesisInvariants(t *testing.T) {
+	validator := randomStakingAddress()
+	delegator := randomStakingAddress()
+
+	base := dumpedGenesis(validator, delegator)
+	base.CommonPool = *quantity.NewFromUint64(8_001)
+	_, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "do not add up to the allocated tokens")
+
+	base = dumpedGenesis(validator, delegator)
+	base.Delegations[validator][delegator].Shares = *quantity.NewFromUint64(401)
+	_, err = stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.Error(t, err)
+
+	base = dumpedGenesis(validator, delegator)
+	base.DebondingDelegations[validator][delegator][0].Shares = *quantity.NewFromUint64(99)
+	_, err = stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.Error(t, err)
+
+	base = dumpedGenesis(validator, delegator)
+	base.TokenValueExponent = stakinggenesis.MaxTokenValueExponent + 1
+	_, err = stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.Error(t, err)
+}
+
+// unitAccountingGenesis is an accounting genesis whose tokens are base units,
+// with a total supply of 10000.
+func unitAccountingGenesis() *stakinggenesis.AccountingGenesis {
+	return stakinggenesis.NewAccountingGenesis(quantity.NewFromUint64(1), 10_000, 10000, 0, 5000)
+}
+
+func TestTransferBalance(t *testing.T) {
+	genesis := unitAccountingGenesis()
+	from := randomStakingAddress()
+	to := randomStakingAddress()
+	require.NoError(t, genesis.AddAccount(from, quantity.NewFromUint64(1_000)))
+	require.NoError(t, genesis.AddAccount(to, quantity.NewFromUint64(0)))
+
+	require.NoError(t, genesis.TransferBalance(from, to, quantity.NewFromUint64(400)))
+	require.Error(t, genesis.TransferBalance(from, to, quantity.NewFromUint64(601)))
+	require.Error(t, genesis.TransferBalance(from, randomStakingAddress(), quantity.NewFromUint64(1)))
+	require.NoError(t, genesis.CheckInvariants())
+
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	requireQuantityEqual(t, partial.Ledger[from].General.Balance, 600)
+	requireQuantityEqual(t, partial.Ledger[to].General.Balance, 400)
+	requireQuantityEqual(t, partial.CommonPool, 9_000)
+}
+
+func TestRemoveDelegation(t *testing.T) {
+	genesis := unitAccountingGenesis()
+	entity := randomStakingAddress()
+	delegator := randomStakingAddress()
+	require.NoError(t, genesis.AddAccount(entity, quantity.NewFromUint64(100)))
+	require.NoError(t, genesis.AddAccount(delegator, quantity.NewFromUint64(1_000)))
+	require.NoError(t, genesis.AddDelegation(entity, entity, quantity.NewFromUint64(100)))
+	require.NoError(t, genesis.AddDelegation(delegator, entity, quantity.NewFromUint64(1_000)))
+
+	// Partially, then the rest of the delegation.
+	require.NoError(t, genesis.RemoveDelegation(delegator, entity, quantity.NewFromUint64(400)))
+	require.NoError(t, genesis.CheckInvariants())
+	require.Error(t, genesis.RemoveDelegation(delegator, entity, quantity.NewFromUint64(601)))
+	require.NoError(t, genesis.RemoveDelegation(delegator, entity, nil))
+	require.NoError(t, genesis.CheckInvariants())
+	require.Error(t, genesis.RemoveDelegation(delegator, entity, nil))
+
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	requireQuantityEqual(t, partial.Ledger[delegator].General.Balance, 1_000)
+	requireQuantityEqual(t, partial.Ledger[entity].Escrow.Active.Balance, 100)
+	requireQuantityEqual(t, partial.Ledger[entity].Escrow.Active.TotalShares, 100)
+	require.Len(t, partial.Ledger[entity].Escrow.CommissionSchedule.Rates, 1)
+	requireQuantityEqual(t, partial.CommonPool, 8_900)
+
+	// The commission schedule is cleared with the last delegation.
+	require.NoError(t, genesis.RemoveDelegation(entity, entity, nil))
+	partial, err = genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	require.Empty(t, partial.Delegations)
+	require.Empty(t, partial.Ledger[entity].Escrow.CommissionSchedule.Rates)
+	require.Empty(t, partial.Ledger[entity].Escrow.CommissionSchedule.Bounds)
+	requireQuantityEqual(t, partial.Ledger[entity].General.Balance, 100)
+}
+
+func TestRemoveAccount(t *testing.T) {
+	genesis := unitAccountingGenesis()
+	entity := randomStakingAddress()
+	other := randomStakingAddress()
+	delegator := randomStakingAddress()
+	require.NoError(t, genesis.AddAccount(entity, quantity.NewFromUint64(1_000)))
+	require.NoError(t, genesis.AddAccount(other, quantity.NewFromUint64(100)))
+	require.NoError(t, genesis.AddAccount(delegator, quantity.NewFromUint64(500)))
+	require.NoError(t, genesis.AddDelegation(entity, entity, quantity.NewFromUint64(600)))
+	require.NoError(t, genesis.AddDelegation(entity, other, quantity.NewFromUint64(300)))
+	require.NoError(t, genesis.AddDelegation(delegator, entity, quantity.NewFromUint64(200)))
+
+	require.NoError(t, genesis.RemoveAccount(entity))
+	require.NoError(t, genesis.CheckInvariants())
+	require.False(t, genesis.HasAccount(entity))
+	require.Error(t, genesis.RemoveAccount(entity))
+
+	// The delegator is refunded, everything held by the entity, including
+	// its delegation to other, goes back to the common pool.
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	requireQuantityEqual(t, partial.Ledger[delegator].General.Balance, 500)
+	requireQuantityEqual(t, partial.Ledger[other].Escrow.Active.Balance, 0)
+	require.Empty(t, partial.Ledger[other].Escrow.CommissionSchedule.Rates)
+	require.Empty(t, partial.Delegations)
+	requireQuantityEqual(t, partial.CommonPool, 9_400)
+}
+
+func TestRemoveAccountFromGenesis(t *testing.T) {
+	validator := randomStakingAddress()
+	delegator := randomStakingAddress()
+	base := dumpedGenesis(validator, delegator)
+	base.TokenValueExponent = 0
+
+	genesis, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.NoError(t, err)
+
+	// Shares are refunded at the rate of the pools, 400 of 1000 active
+	// shares are 480 base units and the debonding pool is 1:1.
+	require.NoError(t, genesis.RemoveAccount(validator))
+	require.NoError(t, genesis.CheckInvariants())
+
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	require.Len(t, partial.Ledger, 1)
+	requireQuantityEqual(t, partial.Ledger[delegator].General.Balance, 880)
+	require.Empty(t, partial.Delegations)
+	require.Empty(t, partial.DebondingDelegations)
+	requireQuantityEqual(t, partial.CommonPool, 9_110)
+}
+
+func TestRemoveDelegationKeepsBaseCommissionSchedule(t *testing.T) {
+	validator := randomStakingAddress()
+	delegator := randomStakingAddress()
+	base := dumpedGenesis(validator, delegator)
+
+	genesis, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.NoError(t, err)
+	require.NoError(t, genesis.RemoveDelegation(delegator, validator, nil))
+	require.NoError(t, genesis.RemoveDelegation(validator, validator, nil))
+	require.NoError(t, genesis.CheckInvariants())
+
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	requireQuantityEqual(t, partial.Ledger[delegator].General.Balance, 780)
+	requireQuantityEqual(t, partial.Ledger[validator].General.Balance, 1_110)
+	requireQuantityEqual(t, partial.Ledger[validator].Escrow.Active.Balance, 0)
+	requireQuantityEqual(t, partial.Ledger[validator].Escrow.CommissionSchedule.Rates[0].Rate, 1_234)
+}
+
+// rewardedGenesis returns an accounting genesis in base units with a
+// validator whose escrow of 1200 base units has 1000 shares.
+func rewardedGenesis(t *testing.T) (genesis *stakinggenesis.AccountingGenesis, validator, delegator staking.Address) {
+	validator = randomStakingAddress()
+	delegator = randomStakingAddress()
+	base := dumpedGenesis(validator, delegator)
+	base.TokenValueExponent = 0
+
+	genesis, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.NoError(t, err)
+	return genesis, validator, delegator
+}
+
+func TestDelegateToRewardedPool(t *testing.T) {
+	genesis, validator, delegator := rewardedGenesis(t)
+
+	// shares = 100 * 1000 / 1200, rounded down, and the pool keeps all of
+	// the base units.
+	require.NoError(t, genesis.Delegate(delegator, validator, quantity.NewFromUint64(100)))
+	require.NoError(t, genesis.CheckInvariants())
+
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	requireQuantityEqual(t, partial.Delegations[validator][delegator].Shares, 483)
+	requireQuantityEqual(t, partial.Ledger[validator].Escrow.Active.Balance, 1_300)
+	requireQuantityEqual(t, partial.Ledger[validator].Escrow.Active.TotalShares, 1_083)
+	requireQuantityEqual(t, partial.Ledger[delegator].General.Balance, 200)
+
+	// An amount worth less than a share is rejected instead of lost.
+	err = genesis.Delegate(delegator, validator, quantity.NewFromUint64(1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "buy no shares")
+	require.Error(t, genesis.Delegate(delegator, validator, quantity.NewFromUint64(201)))
+	require.NoError(t, genesis.CheckInvariants())
+}
+
+func TestUndelegateFromRewardedPool(t *testing.T) {
+	genesis, validator, delegator := rewardedGenesis(t)
+
+	// 120 base units are 100 shares, exactly.
+	require.NoError(t, genesis.RemoveDelegation(delegator, validator, quantity.NewFromUint64(120)))
+	// 100 base units are 83 shares rounded down, worth 99 base units.
+	require.NoError(t, genesis.RemoveDelegation(delegator, validator, quantity.NewFromUint64(100)))
+	require.NoError(t, genesis.CheckInvariants())
+
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+	requireQuantityEqual(t, partial.Delegations[validator][delegator].Shares, 217)
+	requireQuantityEqual(t, partial.Ledger[delegator].General.Balance, 519)
+	requireQuantityEqual(t, partial.Ledger[validator].Escrow.Active.Balance, 981)
+	requireQuantityEqual(t, partial.Ledger[validator].Escrow.Active.TotalShares, 817)
+
+	require.Error(t, genesis.RemoveDelegation(delegator, validator, quantity.NewFromUint64(1_000)))
+	err = genesis.RemoveDelegation(delegator, validator, quantity.NewFromUint64(1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "less than a share")
+}
+
+func TestDelegateToSlashedPool(t *testing.T) {
+	validator := randomStakingAddress()
+	delegator := randomStakingAddress()
+	base := dumpedGenesis(validator, delegator)
+	base.TokenValueExponent = 0
+	// The whole escrow was slashed into the common pool.
+	base.Ledger[validator].Escrow.Active.Balance = *quantity.NewFromUint64(0)
+	base.CommonPool = *quantity.NewFromUint64(9_200)
+
+	genesis, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.NoError(t, err)
+	err = genesis.Delegate(delegator, validator, quantity.NewFromUint64(100))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "have no balance")
+
+	// The remaining shares are worthless but can be removed.
+	require.NoError(t, genesis.RemoveDelegation(delegator, validator, nil))
+	require.NoError(t, genesis.CheckInvariants())
 }
@@ -0,0 +1,403 @@
+package stakinggenesis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlNode "gopkg.in/yaml.v3"
+
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// Adjustment operations.
+const (
+	// OpTransfer moves tokens between the general balances of two accounts.
+	OpTransfer = "transfer"
+	// OpDelegate escrows tokens of an account with another account.
+	OpDelegate = "delegate"
+	// OpUndelegate returns escrowed tokens to the delegator, all of them if
+	// no amount is given.
+	OpUndelegate = "undelegate"
+	// OpSetBalance sets the general balance of an account, the difference is
+	// taken from, or returned to, the common pool.
+	OpSetBalance = "set-balance"
+	// OpRemoveEntity removes an account, its delegators are refunded and its
+	// balances are returned to the common pool.
+	OpRemoveEntity = "remove-entity"
+	// OpAddAccount adds an account funded from the common pool.
+	OpAddAccount = "add-account"
+)
+
+// Report fields of a BalanceChange.
+const (
+	FieldGeneralBalance   = "general_balance"
+	FieldEscrowBalance    = "escrow_balance"
+	FieldDebondingBalance = "debonding_balance"
+	FieldCommonPool       = "common_pool"
+)
+
+// accountFields are the fields of an account that are reported.
+var accountFields = []string{FieldGeneralBalance, FieldEscrowBalance, FieldDebondingBalance}
+
+// adjustmentFields are the fields every operation requires besides the op
+// and the reason.
+var adjustmentFields = map[string][]string{
+	OpTransfer:     {"from", "to", "amount"},
+	OpDelegate:     {"from", "to", "amount"},
+	OpUndelegate:   {"from", "to"},
+	OpSetBalance:   {"account", "amount"},
+	OpRemoveEntity: {"account"},
+	OpAddAccount:   {"account", "amount"},
+}
+
+// optionalAdjustmentFields are the fields an operation may omit.
+var optionalAdjustmentFields = map[string][]string{
+	OpUndelegate: {"amount"},
+}
+
+// Adjustment is a change to the staking ledger applied after the allocations.
+// Accounts are referred to by the names of the staking config accounts, the
+// names of entity packages or by address. Amounts are whole tokens.
+type Adjustment struct {
+	Op     string `yaml:"op"`
+	Reason string `yaml:"reason"`
+	From   string `yaml:"from"`
+	To     string `yaml:"to"`
+	// Account is the account of set-balance, remove-entity and add-account,
+	// add-account requires an address.
+	Account string  `yaml:"account"`
+	Amount  *uint64 `yaml:"amount"`
+
+	// line is the yaml line of the adjustment.
+	line int
+}
+
+// Adjustments is an ordered list of ledger adjustments.
+type Adjustments struct {
+	Adjustments []*Adjustment `yaml:"adjustments"`
+}
+
+// AdjustmentError is a problem with an adjustment.
+type AdjustmentError struct {
+	// Index is the position of the adjustment in the list, from 0.
+	Index int
+	// Line is the yaml line of the adjustment, 0 if unknown.
+	Line int
+	Op   string
+	Err  error
+}
+
+func (e *AdjustmentError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("adjustment %d (%s): %s", e.Index, e.Op, e.Err)
+	}
+	return fmt.Sprintf("line %d: adjustment %d (%s): %s", e.Line, e.Index, e.Op, e.Err)
+}
+
+func (e *AdjustmentError) Unwrap() error {
+	return e.Err
+}
+
+// AdjustmentErrors are the problems with a list of adjustments.
+type AdjustmentErrors []*AdjustmentError
+
+func (e AdjustmentErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d problem(s) with the adjustments: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// LoadAdjustments loads ledger adjustments from a yaml file. Unknown keys are
+// rejected and every adjustment is validated, the errors point at the yaml
+// line of the adjustment.
+func LoadAdjustments(path string) (*Adjustments, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var adjustments Adjustments
+	if err = yaml.UnmarshalStrict(b, &adjustments); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// yaml.v2 does not keep the positions of the list items.
+	var nodes struct {
+		Adjustments []yamlNode.Node `yaml:"adjustments"`
+	}
+	if err = yamlNode.Unmarshal(b, &nodes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, adjustment := range adjustments.Adjustments {
+		if i < len(nodes.Adjustments) {
+			adjustment.line = nodes.Adjustments[i].Line
+		}
+	}
+
+	if err = adjustments.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &adjustments, nil
+}
+
+// validate checks that every adjustment is a known operation with a reason and
+// the fields it requires.
+func (a *Adjustments) validate() error {
+	var errs AdjustmentErrors
+	for i, adjustment := range a.Adjustments {
+		fail := func(err error) {
+			errs = append(errs, &AdjustmentError{Index: i, Line: adjustment.line, Op: adjustment.Op, Err: err})
+		}
+
+		fields, ok := adjustmentFields[adjustment.Op]
+		if !ok {
+			fail(fmt.Errorf("unknown operation %q", adjustment.Op))
+			continue
+		}
+		if strings.TrimSpace(adjustment.Reason) == "" {
+			fail(fmt.Errorf("reason: required field is missing"))
+		}
+
+		set := map[string]bool{
+			"from":    adjustment.From != "",
+			"to":      adjustment.To != "",
+			"account": adjustment.Account != "",
+			"amount":  adjustment.Amount != nil,
+		}
+		used := make(map[string]bool)
+		for _, field := range fields {
+			used[field] = true
+			if !set[field] {
+				fail(fmt.Errorf("%s: required field is missing", field))
+			}
+		}
+		for _, field := range optionalAdjustmentFields[adjustment.Op] {
+			used[field] = true
+		}
+		for _, field := range []string{"from", "to", "account", "amount"} {
+			if set[field] && !used[field] {
+				fail(fmt.Errorf("%s: not used by the operation", field))
+			}
+		}
+
+		if adjustment.Amount != nil && *adjustment.Amount == 0 && adjustment.Op != OpSetBalance {
+			fail(fmt.Errorf("amount: must be greater than zero"))
+		}
+		if adjustment.Op == OpAddAccount && adjustment.Account != "" {
+			var address staking.Address
+			if err := address.UnmarshalText([]byte(adjustment.Account)); err != nil {
+				fail(fmt.Errorf("account: malformed address %q: %w", adjustment.Account, err))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// BalanceChange is a balance changed by an adjustment, in base units.
+type BalanceChange struct {
+	// Account is the name of the account, or its address if it has none.
+	// It is empty for the common pool.
+	Account string            `json:"account,omitempty"`
+	Field   string            `json:"field"`
+	Before  quantity.Quantity `json:"before"`
+	After   quantity.Quantity `json:"after"`
+}
+
+// AdjustmentResult are the balances changed by an adjustment.
+type AdjustmentResult struct {
+	Index   int             `json:"index"`
+	Op      string          `json:"op"`
+	Reason  string          `json:"reason"`
+	Changes []BalanceChange `json:"changes"`
+}
+
+// AdjustmentReport attributes the changes to the ledger to the reasons of the
+// adjustments, in the order they were applied.
+type AdjustmentReport []*AdjustmentResult
+
+// adjuster applies adjustments to an AccountingGenesis.
+type adjuster struct {
+	genesis *AccountingGenesis
+	// names maps the names of accounts and entities to their addresses.
+	names map[string]staking.Address
+	// labels maps addresses to the names used in the report.
+	labels map[staking.Address]string
+}
+
+func newAdjuster(genesis *AccountingGenesis, names map[string]staking.Address) *adjuster {
+	labels := make(map[staking.Address]string)
+	for name, address := range names {
+		labels[address] = name
+	}
+	return &adjuster{
+		genesis: genesis,
+		names:   names,
+		labels:  labels,
+	}
+}
+
+// resolve returns the address of an account name or address.
+func (a *adjuster) resolve(account string) (staking.Address, error) {
+	if address, ok := a.names[strings.ToLower(account)]; ok {
+		return address, nil
+	}
+	var address staking.Address
+	if err := address.UnmarshalText([]byte(account)); err != nil {
+		return address, fmt.Errorf("unknown account %q", account)
+	}
+	return address, nil
+}
+
+func (a *adjuster) label(address staking.Address) string {
+	if name, ok := a.labels[address]; ok {
+		return name
+	}
+	return address.String()
+}
+
+// balanceKey identifies a balance of an account in a snapshot.
+type balanceKey struct {
+	address staking.Address
+	field   string
+}
+
+// snapshot returns the balances of every account and the common pool.
+func (a *adjuster) snapshot() (map[balanceKey]quantity.Quantity, *quantity.Quantity, error) {
+	balances := make(map[balanceKey]quantity.Quantity)
+	for address, account := range a.genesis.ledger {
+		balances[balanceKey{address, FieldGeneralBalance}] = *account.General.Balance.Clone()
+		balances[balanceKey{address, FieldEscrowBalance}] = *account.Escrow.Active.Balance.Clone()
+		balances[balanceKey{address, FieldDebondingBalance}] = *account.Escrow.Debonding.Balance.Clone()
+	}
+	commonPool, err := a.genesis.commonPool()
+	if err != nil {
+		return nil, nil, err
+	}
+	return balances, commonPool, nil
+}
+
+// apply applies a single adjustment and returns the balances it changed.
+func (a *adjuster) apply(index int, adjustment *Adjustment) (*AdjustmentResult, error) {
+	var amount *quantity.Quantity
+	if adjustment.Amount != nil {
+		amount = quantity.NewFromUint64(*adjustment.Amount)
+	}
+
+	var addresses []staking.Address
+	for _, account := range []string{adjustment.From, adjustment.To, adjustment.Account} {
+		if account == "" {
+			continue
+		}
+		address, err := a.resolve(account)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+
+	before, commonPoolBefore, err := a.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	switch adjustment.Op {
+	case OpTransfer:
+		err = a.genesis.TransferBalance(addresses[0], addresses[1], amount)
+	case OpDelegate:
+		err = a.genesis.Delegate(addresses[0], addresses[1], amount)
+	case OpUndelegate:
+		err = a.genesis.RemoveDelegation(addresses[0], addresses[1], amount)
+	case OpSetBalance:
+		err = a.genesis.SetBalance(addresses[0], amount)
+	case OpRemoveEntity:
+		err = a.genesis.RemoveAccount(addresses[0])
+	case OpAddAccount:
+		err = a.genesis.AddAccount(addresses[0], amount)
+	default:
+		err = fmt.Errorf("unknown operation %q", adjustment.Op)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	after, commonPoolAfter, err := a.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	// The accounts of the adjustment are reported first, then the other
+	// accounts it changed, like refunded delegators, in address order.
+	named := make(map[staking.Address]bool)
+	for _, address := range addresses {
+		named[address] = true
+	}
+	others := make(map[staking.Address]bool)
+	for _, snapshot := range []map[balanceKey]quantity.Quantity{before, after} {
+		for key := range snapshot {
+			if !named[key.address] {
+				others[key.address] = true
+			}
+		}
+	}
+	changed := make([]staking.Address, 0, len(others))
+	for address := range others {
+		changed = append(changed, address)
+	}
+	changed = append(addresses, sortAddresses(changed)...)
+
+	result := &AdjustmentResult{
+		Index:   index,
+		Op:      adjustment.Op,
+		Reason:  adjustment.Reason,
+		Changes: make([]BalanceChange, 0),
+	}
+	addChange := func(account, field string, before, after quantity.Quantity) {
+		if before.Cmp(&after) != 0 {
+			result.Changes = append(result.Changes, BalanceChange{
+				Account: account,
+				Field:   field,
+				Before:  before,
+				After:   after,
+			})
+		}
+	}
+	reported := make(map[staking.Address]bool)
+	for _, address := range changed {
+		if reported[address] {
+			continue
+		}
+		reported[address] = true
+		for _, field := range accountFields {
+			key := balanceKey{address, field}
+			addChange(a.label(address), field, before[key], after[key])
+		}
+	}
+	addChange("", FieldCommonPool, *commonPoolBefore, *commonPoolAfter)
+	return result, nil
+}
+
+// ApplyAdjustments applies the adjustments to the genesis in order. names maps
+// the names that adjustments may use to refer to accounts to their addresses.
+// The first adjustment that fails stops the application.
+func ApplyAdjustments(genesis *AccountingGenesis, adjustments *Adjustments, names map[string]staking.Address) (AdjustmentReport, error) {
+	adjuster := newAdjuster(genesis, names)
+
+	report := make(AdjustmentReport, 0, len(adjustments.Adjustments))
+	for i, adjustment := range adjustments.Adjustments {
+		result, err := adjuster.apply(i, adjustment)
+		if err != nil {
+			return nil, &AdjustmentError{Index: i, Line: adjustment.line, Op: adjustment.Op, Err: err}
+		}
+		report = append(report, result)
+	}
+	return report, nil
+}
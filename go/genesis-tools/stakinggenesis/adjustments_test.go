@@ -0,0 +1,240 @@
+package stakinggenesis_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+const adjustmentsFixturePath = "fixtures/adjustments.yaml"
+
+func writeAdjustments(t *testing.T, adjustments string) string {
+	dir, err := ioutil.TempDir("", "adjustments")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "adjustments.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(adjustments), 0o644))
+	return path
+}
+
+func adjustedGenesisOptions() stakinggenesis.GenesisOptions {
+	options := genericGenesisOptions([]string{
+		"test1",
+		"test2",
+		"test3",
+		"test4",
+	})
+	options.ConfigurationPath = "fixtures/staking_ledger_config.yaml"
+	options.AllocationsPath = "fixtures/allocations.csv"
+	options.AdjustmentsPath = adjustmentsFixturePath
+	return options
+}
+
+func TestLoadAdjustments(t *testing.T) {
+	adjustments, err := stakinggenesis.LoadAdjustments(adjustmentsFixturePath)
+	require.NoError(t, err)
+	require.Len(t, adjustments.Adjustments, 7)
+	require.Equal(t, stakinggenesis.OpUndelegate, adjustments.Adjustments[4].Op)
+	require.Nil(t, adjustments.Adjustments[4].Amount)
+
+	_, err = stakinggenesis.LoadAdjustments(writeAdjustments(t, `
+adjustments:
+  - op: transfer
+    from: account1
+    too: test4
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "field too not found")
+
+	_, err = stakinggenesis.LoadAdjustments(writeAdjustments(t, `
+adjustments:
+  - op: transfer
+    from: account1
+    amount: 0
+  - op: remove-entity
+    account: test4
+    amount: 10
+    reason: "gone"
+  - op: burn
+    reason: "typo"
+  - op: add-account
+    account: test5
+    amount: 10
+    reason: "new"
+`))
+	var errs stakinggenesis.AdjustmentErrors
+	require.True(t, errors.As(err, &errs), "unexpected error: %v", err)
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	require.Equal(t, []string{
+		"line 3: adjustment 0 (transfer): reason: required field is missing",
+		"line 3: adjustment 0 (transfer): to: required field is missing",
+		"line 3: adjustment 0 (transfer): amount: must be greater than zero",
+		"line 6: adjustment 1 (remove-entity): amount: not used by the operation",
+		"line 10: adjustment 2 (burn): unknown operation \"burn\"",
+	}, msgs[:5])
+	require.Len(t, msgs, 6)
+	require.Contains(t, msgs[5], "line 12: adjustment 3 (add-account): account: malformed address \"test5\"")
+}
+
+func TestGenerateStakingLedgerWithAdjustments(t *testing.T) {
+	options := adjustedGenesisOptions()
+	genesis, result, err := stakinggenesis.CreateWithReport(options)
+	report := result.Adjustments
+	require.NoError(t, err)
+
+	validator := newValidator(genesis, options.Entities)
+	validator.addAccount("custody", "oasis1qqfjknq5jlelnfd0xtc38u9t25u467nasuzytpe3")
+
+	validator.requireCorrectTotals(t,
+		6_699_999_600_000_000_000,
+		10_000_000_000_000_000_000,
+	)
+	validator.requireGeneralBalance(t, "account1", 1_899_999_000_000_000_000)
+	validator.requireGeneralBalance(t, "test1", 0)
+	validator.requireEscrowBalance(t, "test2", 159_999_900_000_000_000)
+	validator.requireDelegationShares(t, "account1", "test2", 60_000_000_000_000_000)
+	validator.requireEscrowBalance(t, "test3", 140_000_900_000_000_000)
+	validator.requireDelegationShares(t, "account1", "test3", 40_000_000_000_000_000)
+	validator.requireGeneralBalance(t, "custody", 500_000_000_000)
+	require.NotContains(t, genesis.Ledger, validator.entityAddress("test4"))
+	require.NotContains(t, genesis.Delegations, validator.entityAddress("test4"))
+
+	// Every change is attributed to the adjustment that made it.
+	require.Len(t, report, 7)
+	require.Equal(t, "Rewards of test1 failed KYC", report[3].Reason)
+	require.Equal(t, []stakinggenesis.BalanceChange{
+		{
+			Account: "test1",
+			Field:   stakinggenesis.FieldGeneralBalance,
+			Before:  *quantity.NewFromUint64(100_000_000_000),
+			After:   *quantity.NewFromUint64(0),
+		},
+		{
+			Field:  stakinggenesis.FieldCommonPool,
+			Before: *quantity.NewFromUint64(6_699_999_000_000_000_000),
+			After:  *quantity.NewFromUint64(6_699_999_100_000_000_000),
+		},
+	}, report[3].Changes)
+
+	require.Equal(t, stakinggenesis.OpUndelegate, report[1].Op)
+	require.Len(t, report[1].Changes, 2)
+	require.Equal(t, "account1", report[1].Changes[0].Account)
+	require.Equal(t, stakinggenesis.FieldGeneralBalance, report[1].Changes[0].Field)
+	require.Equal(t, "test2", report[1].Changes[1].Account)
+	require.Equal(t, stakinggenesis.FieldEscrowBalance, report[1].Changes[1].Field)
+
+	require.Equal(t, "oasis1qqfjknq5jlelnfd0xtc38u9t25u467nasuzytpe3", report[6].Changes[0].Account)
+}
+
+func TestGenerateStakingLedgerRemoveEntity(t *testing.T) {
+	options := adjustedGenesisOptions()
+	options.AdjustmentsPath = writeAdjustments(t, `
+adjustments:
+  - op: remove-entity
+    account: test2
+    reason: "KYC failed"
+`)
+	genesis, result, err := stakinggenesis.CreateWithReport(options)
+	report := result.Adjustments
+	require.NoError(t, err)
+
+	// The delegation of account1 is refunded, the rest of test2 goes back to
+	// the common pool.
+	validator := newValidator(genesis, options.Entities)
+	validator.requireCorrectTotals(t,
+		6_799_999_000_000_000_000,
+		10_000_000_000_000_000_000,
+	)
+	validator.requireGeneralBalance(t, "account1", 1_999_999_000_000_000_000)
+	require.NotContains(t, genesis.Ledger, validator.entityAddress("test2"))
+	require.Len(t, report[0].Changes, 4)
+}
+
+func TestApplyAdjustmentsRemoveEntityWithDebonding(t *testing.T) {
+	validator := randomStakingAddress()
+	delegator := randomStakingAddress()
+	base := dumpedGenesis(validator, delegator)
+	base.TokenValueExponent = 0
+	genesis, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.NoError(t, err)
+
+	adjustments, err := stakinggenesis.LoadAdjustments(writeAdjustments(t, `
+adjustments:
+  - op: remove-entity
+    account: validator
+    reason: "KYC failed"
+`))
+	require.NoError(t, err)
+	report, err := stakinggenesis.ApplyAdjustments(genesis, adjustments, map[string]staking.Address{
+		"validator": validator,
+	})
+	require.NoError(t, err)
+
+	// The debonding delegation refunded to the delegator is reported as
+	// leaving the debonding balance of the validator, so that the changes
+	// balance out.
+	q := func(v uint64) quantity.Quantity {
+		return *quantity.NewFromUint64(v)
+	}
+	require.Equal(t, []stakinggenesis.BalanceChange{
+		{Account: "validator", Field: stakinggenesis.FieldGeneralBalance, Before: q(390), After: q(0)},
+		{Account: "validator", Field: stakinggenesis.FieldEscrowBalance, Before: q(1_200), After: q(0)},
+		{Account: "validator", Field: stakinggenesis.FieldDebondingBalance, Before: q(100), After: q(0)},
+		{Account: delegator.String(), Field: stakinggenesis.FieldGeneralBalance, Before: q(300), After: q(880)},
+		{Field: stakinggenesis.FieldCommonPool, Before: q(8_000), After: q(9_110)},
+	}, report[0].Changes)
+}
+
+func TestGenerateStakingLedgerWithFailingAdjustments(t *testing.T) {
+	for _, tc := range []struct {
+		adjustments string
+		err         string
+	}{
+		{`
+adjustments:
+  - op: remove-entity
+    account: oasis1qqfjknq5jlelnfd0xtc38u9t25u467nasuzytpe3
+    reason: "not in the ledger"
+`, `line 3: adjustment 0 (remove-entity): cannot remove account`},
+		{`
+adjustments:
+  - op: set-balance
+    account: test1
+    amount: 9000000000
+    reason: "more than the common pool"
+`, `line 3: adjustment 0 (set-balance): allocated tokens`},
+		{`
+adjustments:
+  - op: transfer
+    from: account1
+    to: nobody
+    amount: 1
+    reason: "unknown account"
+`, `unknown account "nobody"`},
+		{`
+adjustments:
+  - op: undelegate
+    from: account2
+    to: test1
+    reason: "no such delegation"
+`, `no delegation from`},
+	} {
+		options := adjustedGenesisOptions()
+		options.AdjustmentsPath = writeAdjustments(t, tc.adjustments)
+		_, err := stakinggenesis.Create(options)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), tc.err)
+	}
+}
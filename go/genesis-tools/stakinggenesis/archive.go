@@ -0,0 +1,74 @@
+package stakinggenesis
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxPackageFileSize is the largest file extracted from an entity package.
+// Descriptors are a few kilobytes.
+const maxPackageFileSize = 1 << 20
+
+// entityPackageFiles are the only files extracted from an entity package
+// archive.
+var entityPackageFiles = map[string]bool{
+	"entity/entity.json":         true,
+	"entity/entity_genesis.json": true,
+	"node/node_genesis.json":     true,
+}
+
+// UnpackEntityPackage extracts a gzipped tar entity package into destDir.
+// Only the expected descriptor files are extracted, everything else in the
+// archive is ignored.
+func UnpackEntityPackage(r io.Reader, destDir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("entity package is not gzipped: %w", err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("entity package is not a valid tar archive: %w", err)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		if header.Typeflag != tar.TypeReg || !entityPackageFiles[name] {
+			continue
+		}
+		if header.Size > maxPackageFileSize {
+			return fmt.Errorf(`entity package file "%s" is too large`, name)
+		}
+
+		if err = extractFile(reader, filepath.Join(destDir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(r io.Reader, filePath string) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(f, io.LimitReader(r, maxPackageFileSize)); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,224 @@
+package stakinggenesis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yamlNode "gopkg.in/yaml.v3"
+
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// MaxTokenValueExponent is the largest supported token_value_exponent.
+const MaxTokenValueExponent = 18
+
+// requiredConfigFields are the staking configuration fields that must be set,
+// as paths of yaml keys.
+var requiredConfigFields = []string{
+	"accounts",
+	"csv_options",
+	"csv_options.kyc_label",
+	"csv_options.entity_package_submitted_label",
+	"csv_options.entity_package_name_label",
+	"csv_options.funding_label",
+	"minimum_balance",
+	"token_value_exponent",
+	"token_symbol",
+	"total_supply",
+	"commission_rate_max",
+	"commission_rate_min",
+	"commission_rate",
+}
+
+// requiredAccountFields are the fields every account must set.
+var requiredAccountFields = []string{"amount", "address"}
+
+// ConfigError is a problem with a field of the staking configuration.
+type ConfigError struct {
+	// Line is the yaml line of the field, 0 if the field is missing.
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Field, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigErrors are the problems with a staking configuration.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d problem(s) with the staking config: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// caseInsensitiveKeys are the mappings whose keys are names that are
+// lowercased when decoded.
+var caseInsensitiveKeys = map[string]bool{
+	"accounts":           true,
+	"test_only_entities": true,
+}
+
+// configLines maps the paths of the yaml keys of a document, joined by dots,
+// to their lines. It also returns the names of caseInsensitiveKeys that only
+// differ in case, which would silently be merged when decoded.
+func configLines(b []byte) (map[string]int, ConfigErrors, error) {
+	var root yamlNode.Node
+	if err := yamlNode.Unmarshal(b, &root); err != nil {
+		return nil, nil, err
+	}
+
+	lines := make(map[string]int)
+	var duplicates ConfigErrors
+	var walk func(prefix string, node *yamlNode.Node)
+	walk = func(prefix string, node *yamlNode.Node) {
+		switch node.Kind {
+		case yamlNode.DocumentNode:
+			for _, child := range node.Content {
+				walk(prefix, child)
+			}
+		case yamlNode.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i]
+				path := strings.ToLower(key.Value)
+				if prefix != "" {
+					path = prefix + "." + path
+				}
+				if line, ok := lines[path]; ok && caseInsensitiveKeys[prefix] {
+					duplicates = append(duplicates, &ConfigError{
+						Line:  key.Line,
+						Field: prefix + "." + key.Value,
+						Err:   fmt.Errorf("duplicate of the name on line %d, names are case-insensitive", line),
+					})
+					continue
+				}
+				lines[path] = key.Line
+				walk(path, node.Content[i+1])
+			}
+		}
+	}
+	walk("", &root)
+	return lines, duplicates, nil
+}
+
+// validate checks a decoded staking configuration and parses the account
+// amounts and addresses. lines are the lines of the yaml keys, used to locate the
+// problems.
+func (g *GenesisConfig) validate(lines map[string]int) error {
+	var errs ConfigErrors
+	fail := func(field string, err error) {
+		errs = append(errs, &ConfigError{Line: lines[field], Field: field, Err: err})
+	}
+
+	for _, field := range requiredConfigFields {
+		if _, ok := lines[field]; !ok {
+			fail(field, fmt.Errorf("required field is missing"))
+		}
+	}
+
+	if g.TokenValueExponent > MaxTokenValueExponent {
+		fail("token_value_exponent", fmt.Errorf("%d exceeds the maximum of %d", g.TokenValueExponent, MaxTokenValueExponent))
+	}
+	if strings.TrimSpace(g.TokenSymbol) == "" {
+		fail("token_symbol", fmt.Errorf("must not be empty"))
+	}
+
+	if g.CommissionRateMin > g.CommissionRateMax {
+		fail("commission_rate_min", fmt.Errorf("%d is greater than commission_rate_max %d", g.CommissionRateMin, g.CommissionRateMax))
+	}
+	if g.CommissionRate < g.CommissionRateMin || g.CommissionRate > g.CommissionRateMax {
+		fail("commission_rate", fmt.Errorf("%d is outside of [commission_rate_min %d, commission_rate_max %d]",
+			g.CommissionRate, g.CommissionRateMin, g.CommissionRateMax))
+	}
+	if quantity.NewFromUint64(g.CommissionRateMax).Cmp(staking.CommissionRateDenominator) > 0 {
+		fail("commission_rate_max", fmt.Errorf("%d exceeds the commission rate denominator %s",
+			g.CommissionRateMax, staking.CommissionRateDenominator))
+	}
+
+	for status := range g.KYCPolicies {
+		if !kycStatuses[status] {
+			fail("kyc_policies."+string(status), fmt.Errorf("unknown KYC status"))
+		}
+	}
+	for value, status := range g.CSVOptions.KycValues {
+		if !kycStatuses[status] {
+			fail("csv_options.kyc_values."+strings.ToLower(value), fmt.Errorf("unknown KYC status %q", status))
+		}
+	}
+	for name, allocation := range g.TestOnlyEntities {
+		if allocation.KYC == nil {
+			continue
+		}
+		if err := allocation.KYC.validate(); err != nil {
+			fail("test_only_entities."+name+".kyc", err)
+		}
+	}
+	if g.KYCRedirectAccount != "" {
+		if _, ok := g.Accounts[strings.ToLower(g.KYCRedirectAccount)]; !ok {
+			fail("kyc_redirect_account", fmt.Errorf("unknown account %q", g.KYCRedirectAccount))
+		}
+	}
+
+	// Accounts are checked in the order of the document, so a duplicate
+	// address is reported on the later account.
+	names := make([]string, 0, len(g.Accounts))
+	for name := range g.Accounts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return lines["accounts."+names[i]] < lines["accounts."+names[j]]
+	})
+	addresses := make(map[staking.Address]string)
+	for _, name := range names {
+		account := g.Accounts[name]
+		prefix := "accounts." + name
+		for _, field := range requiredAccountFields {
+			if _, ok := lines[prefix+"."+field]; !ok {
+				fail(prefix+"."+field, fmt.Errorf("required field is missing"))
+			}
+		}
+		if _, ok := lines[prefix+".amount"]; ok {
+			var err error
+			if account.amount, err = parseUintStrToQuantity(account.rawAmount); err != nil {
+				fail(prefix+".amount", fmt.Errorf("malformed amount %q: %w", account.rawAmount, err))
+			}
+		}
+		if _, ok := lines[prefix+".address"]; !ok {
+			continue
+		}
+
+		field := prefix + ".address"
+		if err := account.address.UnmarshalText([]byte(account.rawAddress)); err != nil {
+			fail(field, fmt.Errorf("malformed address %q: %w", account.rawAddress, err))
+			continue
+		}
+		if other, ok := addresses[account.address]; ok {
+			fail(field, fmt.Errorf("address %s is already used by account %s", account.address, other))
+			continue
+		}
+		addresses[account.address] = name
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	// Some of the fields are checked in map order, the problems are listed
+	// in the order of the document, missing fields first.
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Line < errs[j].Line
+	})
+	return errs
+}
@@ -0,0 +1,127 @@
+package stakinggenesis_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+)
+
+const configFixturePath = "fixtures/staking_ledger_config.yaml"
+
+// writeConfig writes the fixture configuration with the replacements applied
+// and returns its path.
+func writeConfig(t *testing.T, replacements ...string) string {
+	b, err := ioutil.ReadFile(configFixturePath)
+	require.NoError(t, err)
+	config := strings.NewReplacer(replacements...).Replace(string(b))
+
+	dir, err := ioutil.TempDir("", "stakingconfig")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "staking_config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(config), 0o644))
+	return path
+}
+
+func requireConfigErrors(t *testing.T, err error, expected ...string) {
+	var errs stakinggenesis.ConfigErrors
+	require.True(t, errors.As(err, &errs), "unexpected error: %v", err)
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	require.Equal(t, expected, msgs)
+}
+
+func TestLoadGenesisConfig(t *testing.T) {
+	config, err := stakinggenesis.LoadGenesisConfig(configFixturePath)
+	require.NoError(t, err)
+	require.Equal(t, uint8(9), config.TokenValueExponent)
+	require.Len(t, config.Accounts, 2)
+
+	_, err = stakinggenesis.LoadGenesisConfig("../../../.github/staking_config.yaml")
+	require.NoError(t, err)
+}
+
+func TestLoadGenesisConfigUnknownFields(t *testing.T) {
+	_, err := stakinggenesis.LoadGenesisConfig(writeConfig(t, "commission_rate: 5000", "comission_rate: 5000"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 54: field comission_rate not found")
+
+	_, err = stakinggenesis.LoadGenesisConfig(writeConfig(t, `csv_label: "Account Two"`, `csv_lable: "Account Two"`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 19: field csv_lable not found")
+}
+
+func TestLoadGenesisConfigValidation(t *testing.T) {
+	_, err := stakinggenesis.LoadGenesisConfig(writeConfig(t,
+		"commission_rate_min: 0", "commission_rate_min: 30000",
+		"commission_rate: 5000", "commission_rate: 25000",
+	))
+	requireConfigErrors(t, err,
+		"line 53: commission_rate_min: 30000 is greater than commission_rate_max 20000",
+		"line 54: commission_rate: 25000 is outside of [commission_rate_min 30000, commission_rate_max 20000]",
+	)
+
+	_, err = stakinggenesis.LoadGenesisConfig(writeConfig(t,
+		"commission_rate_max: 20000", "commission_rate_max: 200000",
+		"token_value_exponent: 9", "token_value_exponent: 19",
+	))
+	requireConfigErrors(t, err,
+		"line 48: token_value_exponent: 19 exceeds the maximum of 18",
+		"line 52: commission_rate_max: 200000 exceeds the commission rate denominator 100000",
+	)
+
+	_, err = stakinggenesis.LoadGenesisConfig(writeConfig(t,
+		"total_supply: 10000000000\n", "",
+		`  funding_label: "Total Rewards [sum of Quest + Grants, paid out from community & ecosystem]"`, "",
+		`    amount: "1000000000"`, "",
+	))
+	requireConfigErrors(t, err,
+		"csv_options.funding_label: required field is missing",
+		"total_supply: required field is missing",
+		"accounts.account2.amount: required field is missing",
+	)
+}
+
+func TestLoadGenesisConfigAccounts(t *testing.T) {
+	_, err := stakinggenesis.LoadGenesisConfig(writeConfig(t, `amount: "1000000000"`, `amount: "1e9"`))
+	var errs stakinggenesis.ConfigErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	require.Equal(t, 17, errs[0].Line)
+	require.Contains(t, errs[0].Error(), `accounts.account2.amount: malformed amount "1e9"`)
+
+	_, err = stakinggenesis.LoadGenesisConfig(writeConfig(t,
+		"oasis1qz6hdmtth24x5udlvmavufwvy5ac6pvh2cdlehnx", "oasis1qz6hdmtth24x5udlvmavufwvy5ac6pvh2cdlehn",
+	))
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	require.Equal(t, 18, errs[0].Line)
+	require.Equal(t, "accounts.account2.address", errs[0].Field)
+	require.Contains(t, errs[0].Error(), `malformed address "oasis1qz6hdmtth24x5udlvmavufwvy5ac6pvh2cdlehn"`)
+
+	_, err = stakinggenesis.LoadGenesisConfig(writeConfig(t,
+		"oasis1qz6hdmtth24x5udlvmavufwvy5ac6pvh2cdlehnx", "oasis1qz2kz3zkgf6trclyajtyg4jecw7es7p5tutfqaz0",
+	))
+	requireConfigErrors(t, err,
+		"line 18: accounts.account2.address: address oasis1qz2kz3zkgf6trclyajtyg4jecw7es7p5tutfqaz0 is already used by account account1",
+	)
+}
+
+func TestLoadGenesisConfigDuplicateNames(t *testing.T) {
+	// Names are lowercased, accounts that only differ in case would be
+	// merged.
+	_, err := stakinggenesis.LoadGenesisConfig(writeConfig(t, "  account2:\n", "  Account1:\n"))
+	requireConfigErrors(t, err,
+		"line 16: accounts.Account1: duplicate of the name on line 11, names are case-insensitive",
+	)
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io/ioutil"
-	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -20,6 +19,8 @@ type StakingAccounts map[staking.Address]*staking.Account
 
 type StakingDelegations map[staking.Address]map[staking.Address]*staking.Delegation
 
+type StakingDebondingDelegations map[staking.Address]map[staking.Address][]*staking.DebondingDelegation
+
 func parseUintStrToQuantity(s string) (*quantity.Quantity, error) {
 	uintValue, err := strconv.ParseUint(s, 10, 64)
 	if err != nil {
@@ -32,6 +33,8 @@ func parseUintStrToQuantity(s string) (*quantity.Quantity, error) {
 type GenesisAccount struct {
 	amount                      *quantity.Quantity
 	address                     staking.Address
+	rawAmount                   string
+	rawAddress                  string
 	csvLabel                    string
 	outboundDelegations         map[string]*quantity.Quantity
 	testOnlyOutboundDelegations map[string]*quantity.Quantity
@@ -50,18 +53,10 @@ func (g *GenesisAccount) UnmarshalYAML(unmarshal func(interface{}) error) error
 		return err
 	}
 
-	g.amount, err = parseUintStrToQuantity(raw.Amount)
-	if err != nil {
-		return err
-	}
-
-	var address staking.Address
-	err = address.UnmarshalText([]byte(raw.Address))
-	if err != nil {
-		return err
-	}
-
-	g.address = address
+	// The amount and address are parsed when the configuration is
+	// validated, so that errors can point at their lines.
+	g.rawAmount = raw.Amount
+	g.rawAddress = raw.Address
 	g.csvLabel = raw.CsvLabel
 
 	g.testOnlyOutboundDelegations = make(map[string]*quantity.Quantity)
@@ -76,6 +71,12 @@ func (g *GenesisAccount) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return nil
 }
 
+// Address returns the address of the account, it is set once the
+// configuration is loaded.
+func (g *GenesisAccount) Address() staking.Address {
+	return g.address
+}
+
 type GenesisAccounts map[string]*GenesisAccount
 
 func (g *GenesisAccounts) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -91,6 +92,9 @@ func (g *GenesisAccounts) UnmarshalYAML(unmarshal func(interface{}) error) error
 	// Convert each of the values into a quantity
 	for name, account := range raw {
 		// Normalize entity names
+		if _, ok := accounts[strings.ToLower(name)]; ok {
+			return fmt.Errorf("duplicate account name %q, names are case-insensitive", name)
+		}
 		accounts[strings.ToLower(name)] = account
 	}
 
@@ -112,6 +116,9 @@ func (g *GenesisEntityAllocations) UnmarshalYAML(unmarshal func(interface{}) err
 
 	// Normalize entity names
 	for entityName, allocation := range raw {
+		if _, ok := allocations[strings.ToLower(entityName)]; ok {
+			return fmt.Errorf("duplicate entity name %q, names are case-insensitive", entityName)
+		}
 		allocations[strings.ToLower(entityName)] = allocation
 	}
 
@@ -130,6 +137,20 @@ type GenesisConfig struct {
 	CommissionRateMin  uint64                   `yaml:"commission_rate_min"`
 	CommissionRate     uint64                   `yaml:"commission_rate"`
 	CSVOptions         GenesisCSVOptions        `yaml:"csv_options"`
+	// KYCPolicies override the DefaultKYCPolicies of their statuses.
+	KYCPolicies KYCPolicies `yaml:"kyc_policies"`
+	// KYCRedirectAccount is the name of the account credited with the
+	// funding and delegations blocked by KYC. If unset, blocked funding
+	// stays in the common pool and blocked delegations with the delegator.
+	KYCRedirectAccount string `yaml:"kyc_redirect_account"`
+}
+
+// kycPolicy returns the policy of a KYC status.
+func (g *GenesisConfig) kycPolicy(status KYCStatus) KYCPolicy {
+	if policy, ok := g.KYCPolicies[status]; ok {
+		return policy
+	}
+	return DefaultKYCPolicies()[status]
 }
 
 type GenesisCSVOptions struct {
@@ -137,27 +158,42 @@ type GenesisCSVOptions struct {
 	EntityPackageSubmittedLabel string `yaml:"entity_package_submitted_label"`
 	EntityPackageNameLabel      string `yaml:"entity_package_name_label"`
 	FundingLabel                string `yaml:"funding_label"`
+	GithubHandleLabel           string `yaml:"github_handle_label"`
+	// KycReasonLabel and KycDateLabel are the optional columns of the
+	// reason and date of the KYC status.
+	KycReasonLabel string `yaml:"kyc_reason_label"`
+	KycDateLabel   string `yaml:"kyc_date_label"`
+	// KycValues maps values of the KYC column to statuses, in addition to
+	// TRUE, FALSE, blank and the statuses themselves.
+	KycValues map[string]KYCStatus `yaml:"kyc_values"`
 }
 
 type Allocation struct {
 	Delegations map[string]uint64 `yaml:"delegations"`
 	Funds       uint64            `yaml:"funds"`
+	// KYC is the KYC status of the entity, entities without one are exempt.
+	KYC *KYCRecord `yaml:"kyc"`
 }
 
 type EntityAllocationTable interface {
 	All() GenesisEntityAllocations
+	GithubHandles() map[string]bool
 }
 
 type genesisCSV struct {
 	options                     GenesisCSVOptions
 	accounts                    GenesisAccounts
 	kycIndex                    int
+	kycReasonIndex              int
+	kycDateIndex                int
 	entityPackageSubmittedIndex int
 	entityPackageNameIndex      int
 	fundingIndex                int
+	githubHandleIndex           int
 	accountIndices              map[string]int
 	records                     [][]string
 	allocations                 GenesisEntityAllocations
+	githubHandles               map[string]bool
 }
 
 func loadGenesisCSV(path string, options GenesisCSVOptions, accounts GenesisAccounts) (*genesisCSV, error) {
@@ -174,20 +210,31 @@ func loadGenesisCSV(path string, options GenesisCSVOptions, accounts GenesisAcco
 		return nil, err
 	}
 	g := &genesisCSV{
-		options:        options,
-		accounts:       accounts,
-		records:        records,
-		accountIndices: make(map[string]int),
-		allocations:    make(map[string]*Allocation),
+		options:           options,
+		accounts:          accounts,
+		records:           records,
+		githubHandleIndex: -1,
+		kycReasonIndex:    -1,
+		kycDateIndex:      -1,
+		accountIndices:    make(map[string]int),
+		allocations:       make(map[string]*Allocation),
+		githubHandles:     make(map[string]bool),
 	}
 
-	g.mapIndices()
-	g.process()
+	if err = g.mapIndices(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err = g.process(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
 
 	return g, nil
 }
 
 func (g *genesisCSV) mapIndices() error {
+	if len(g.records) == 0 {
+		return fmt.Errorf("missing header row")
+	}
 	found := 0
 	accountLookup := make(map[string]string)
 	// Create a lookup for account labels
@@ -210,12 +257,27 @@ func (g *genesisCSV) mapIndices() error {
 			g.fundingIndex = index
 			found++
 		default:
+			if g.options.GithubHandleLabel != "" && label == g.options.GithubHandleLabel {
+				g.githubHandleIndex = index
+				continue
+			}
+			if g.options.KycReasonLabel != "" && label == g.options.KycReasonLabel {
+				g.kycReasonIndex = index
+				continue
+			}
+			if g.options.KycDateLabel != "" && label == g.options.KycDateLabel {
+				g.kycDateIndex = index
+				continue
+			}
 			// Check if it's one of the accounts
 			if accountName, ok := accountLookup[label]; ok {
 				g.accountIndices[accountName] = index
 			}
 		}
 	}
+	if found < 4 {
+		return fmt.Errorf("header row is missing the kyc, entity package submitted, entity package name or funding column")
+	}
 	return nil
 }
 
@@ -223,6 +285,12 @@ func (g *genesisCSV) process() error {
 	allocations := g.allocations
 
 	for row, record := range g.records[1:] {
+		if g.githubHandleIndex >= 0 {
+			if handle := strings.TrimSpace(record[g.githubHandleIndex]); handle != "" {
+				g.githubHandles[strings.ToLower(handle)] = true
+			}
+		}
+
 		// Skip if no entity package has been submitted
 		if record[g.entityPackageSubmittedIndex] != "TRUE" {
 			continue
@@ -235,13 +303,19 @@ func (g *genesisCSV) process() error {
 			continue
 		}
 
-		var funding uint64
+		kyc, err := g.kycRecord(record)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", row+2, err)
+		}
 
-		// Non-KYC cannot receive funds
-		if record[g.kycIndex] == "TRUE" {
-			value, err := parseHumanReadableNumberToUint64(record[g.fundingIndex])
+		// Whether the funding is allowed is up to the KYC policy, the
+		// funding of a blocked entity may still be redirected so it is
+		// parsed too. Entities without KYC approval may leave it blank.
+		var funding uint64
+		if rawFunding := record[g.fundingIndex]; kyc.Status == KYCApproved || strings.TrimSpace(rawFunding) != "" {
+			value, err := parseHumanReadableNumberToUint64(rawFunding)
 			if err != nil {
-				return err
+				return fmt.Errorf("row %d: %w", row+2, err)
 			}
 			funding = value
 		}
@@ -251,7 +325,7 @@ func (g *genesisCSV) process() error {
 		for accountName, accountIndex := range g.accountIndices {
 			value, err := parseHumanReadableNumberToUint64(record[accountIndex])
 			if err != nil {
-				return err
+				return fmt.Errorf("row %d: %w", row+2, err)
 			}
 			delegations[accountName] = value
 		}
@@ -259,11 +333,31 @@ func (g *genesisCSV) process() error {
 		allocations[entityName] = &Allocation{
 			Delegations: delegations,
 			Funds:       funding,
+			KYC:         kyc,
 		}
 	}
 	return nil
 }
 
+// kycRecord returns the KYC status of a row with its reason and date.
+func (g *genesisCSV) kycRecord(record []string) (*KYCRecord, error) {
+	status, err := parseKYCStatus(record[g.kycIndex], g.options.KycValues)
+	if err != nil {
+		return nil, err
+	}
+	kyc := &KYCRecord{Status: status}
+	if g.kycReasonIndex >= 0 {
+		kyc.Reason = strings.TrimSpace(record[g.kycReasonIndex])
+	}
+	if g.kycDateIndex >= 0 {
+		kyc.Date = strings.TrimSpace(record[g.kycDateIndex])
+	}
+	if err = kyc.validate(); err != nil {
+		return nil, err
+	}
+	return kyc, nil
+}
+
 // Handle numbers with commas
 func parseHumanReadableNumberToUint64(s string) (uint64, error) {
 	noCommas := strings.ReplaceAll(s, ",", "")
@@ -274,6 +368,11 @@ func (g *genesisCSV) All() GenesisEntityAllocations {
 	return g.allocations
 }
 
+// GithubHandles returns the lowercased GitHub handles found in the table.
+func (g *genesisCSV) GithubHandles() map[string]bool {
+	return g.githubHandles
+}
+
 // genesisCreator an implementation of a basic genesis allocations
 // interface
 type genesisCreator struct {
@@ -281,25 +380,81 @@ type genesisCreator struct {
 	config                GenesisConfig
 	entityMappings        map[string]staking.Address
 	entityAllocationTable EntityAllocationTable
+	adjustments           *Adjustments
+	report                *Report
 }
 
-// Create loads a genesis allocation from a yaml file
-func Create(options GenesisOptions) (*staking.Genesis, error) {
-	bytes, err := ioutil.ReadFile(options.ConfigurationPath)
+// LoadGenesisConfig loads the staking genesis configuration from a yaml file.
+// Unknown keys are rejected and the configuration is validated, the errors
+// point at the yaml line of the problem.
+func LoadGenesisConfig(path string) (*GenesisConfig, error) {
+	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	// Names that only differ in case are reported with their lines before
+	// decoding rejects them.
+	lines, duplicates, err := configLines(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(duplicates) > 0 {
+		return nil, fmt.Errorf("%s: %w", path, duplicates)
+	}
 	var config GenesisConfig
-	err = yaml.Unmarshal(bytes, &config)
+	if err = yaml.UnmarshalStrict(bytes, &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err = config.validate(lines); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// LoadEntityAllocationTable loads the allocations table from a CSV using the
+// csv options and accounts of the given configuration.
+func LoadEntityAllocationTable(path string, config *GenesisConfig) (EntityAllocationTable, error) {
+	return loadGenesisCSV(path, config.CSVOptions, config.Accounts)
+}
+
+// Report is what happened to the allocations while creating a staking
+// genesis.
+type Report struct {
+	// Adjustments are the changes made by the adjustments.
+	Adjustments AdjustmentReport `json:"adjustments"`
+	// KYC are the allocations blocked by KYC, ordered by entity.
+	KYC KYCReport `json:"kyc"`
+}
+
+// Create loads a genesis allocation from a yaml file
+func Create(options GenesisOptions) (*staking.Genesis, error) {
+	genesis, _, err := CreateWithReport(options)
+	return genesis, err
+}
+
+// CreateWithReport is Create that also reports what happened to the
+// allocations.
+func CreateWithReport(options GenesisOptions) (*staking.Genesis, *Report, error) {
+	loadedConfig, err := LoadGenesisConfig(options.ConfigurationPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	config := *loadedConfig
 
 	// Load the allocations table from a CSV
 	allocations, err := loadGenesisCSV(options.AllocationsPath, config.CSVOptions, config.Accounts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if options.RequireGithubHandles {
+		if config.CSVOptions.GithubHandleLabel == "" {
+			return nil, nil, fmt.Errorf("github handle check requires csv_options.github_handle_label")
+		}
+		if err = CheckGithubHandles(options.Entities, allocations.GithubHandles()); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	creator := genesisCreator{
@@ -307,14 +462,33 @@ func Create(options GenesisOptions) (*staking.Genesis, error) {
 		options:               options,
 		entityMappings:        make(map[string]staking.Address),
 		entityAllocationTable: allocations,
+		report: &Report{
+			Adjustments: make(AdjustmentReport, 0),
+			KYC:         make(KYCReport, 0),
+		},
+	}
+	if options.AdjustmentsPath != "" {
+		if creator.adjustments, err = LoadAdjustments(options.AdjustmentsPath); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return creator.GenerateGenesis()
+	genesis, err := creator.GenerateGenesis()
+	if err != nil {
+		return nil, nil, err
+	}
+	return genesis, creator.report, nil
 }
 
 func (g *genesisCreator) initializeAccountingGenesis() (*AccountingGenesis, error) {
-	var precision uint64
-	precision = uint64(math.Pow(10.0, float64(g.config.TokenValueExponent)))
+	if base := g.options.BaseGenesis; base != nil {
+		return g.initializeAccountingGenesisFromBase(base)
+	}
+
+	precision, err := Precision(g.config.TokenValueExponent)
+	if err != nil {
+		return nil, err
+	}
 
 	genesis := NewAccountingGenesis(
 		precision,
@@ -334,6 +508,52 @@ func (g *genesisCreator) initializeAccountingGenesis() (*AccountingGenesis, erro
 	return genesis, nil
 }
 
+// initializeAccountingGenesisFromBase seeds the accounting with the base
+// genesis. Accounts of the configuration that are already in the base are
+// kept as they are.
+func (g *genesisCreator) initializeAccountingGenesisFromBase(base *staking.Genesis) (*AccountingGenesis, error) {
+	if base.TokenSymbol != g.config.TokenSymbol || base.TokenValueExponent != g.config.TokenValueExponent {
+		return nil, fmt.Errorf("base genesis token %s (exponent %d) does not match the configured %s (exponent %d)",
+			base.TokenSymbol, base.TokenValueExponent, g.config.TokenSymbol, g.config.TokenValueExponent)
+	}
+
+	// The configured total supply must describe the base, otherwise the
+	// configuration was written for another ledger.
+	totalSupply, err := Precision(g.config.TokenValueExponent)
+	if err != nil {
+		return nil, err
+	}
+	if err = totalSupply.Mul(quantity.NewFromUint64(g.config.TotalSupply)); err != nil {
+		return nil, err
+	}
+	if totalSupply.Cmp(&base.TotalSupply) != 0 {
+		return nil, fmt.Errorf("base genesis total supply %s does not match the configured total supply %s (%d tokens)",
+			&base.TotalSupply, totalSupply, g.config.TotalSupply)
+	}
+
+	genesis, err := NewAccountingGenesisFromGenesis(
+		base,
+		g.config.CommissionRateMax,
+		g.config.CommissionRateMin,
+		g.config.CommissionRate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, account := range g.config.Accounts {
+		if genesis.HasAccount(account.address) {
+			logger.Info("keeping account of the base genesis",
+				"account_name", name, "address", account.address)
+			continue
+		}
+		if err = genesis.AddAccount(account.address, account.amount); err != nil {
+			return nil, err
+		}
+	}
+	return genesis, nil
+}
+
 // addEntityMapping Adds an entity name to address mapping
 func (g *genesisCreator) addEntityMapping(name string, address staking.Address) error {
 	if _, ok := g.entityMappings[name]; ok {
@@ -343,47 +563,120 @@ func (g *genesisCreator) addEntityMapping(name string, address staking.Address)
 	return nil
 }
 
+// accountNames maps the names of the configured accounts and of the entities
+// to their addresses.
+func (g *genesisCreator) accountNames() (map[string]staking.Address, error) {
+	names := make(map[string]staking.Address)
+	for name, account := range g.config.Accounts {
+		names[name] = account.address
+	}
+	for name, address := range g.entityMappings {
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("name %s is used by both an account and an entity", name)
+		}
+		names[name] = address
+	}
+	return names, nil
+}
+
 func (g *genesisCreator) setupAccountsForEntities(genesis *AccountingGenesis, entities GenesisEntityAllocations) error {
+	excluded := make(map[string]bool)
+	for _, name := range g.options.ExcludedEntities {
+		excluded[strings.ToLower(name)] = true
+	}
+
 	// Setup entity accounts and establish self delegation
 	for name, allocation := range entities {
+		if excluded[name] {
+			logger.Warn("skipping allocation of excluded entity package",
+				"entity_name", name)
+			continue
+		}
 		entityAddress, ok := g.entityMappings[name]
 		if !ok {
 			return fmt.Errorf(`account name "%s" is missing from processed entity packages`, name)
 		}
 
-		funds := quantity.NewFromUint64(allocation.Funds)
+		// The allocations of entities that are already in a base genesis
+		// are added on top of their accounts and delegations.
+		exists := genesis.HasAccount(entityAddress)
+		delegate := genesis.AddDelegation
+		if exists {
+			logger.Info("adding allocation to the entity account of the base genesis",
+				"entity_name", name, "address", entityAddress)
+			delegate = genesis.Delegate
+		}
+
+		kyc := allocation.KYC
+		if kyc == nil {
+			kyc = &KYCRecord{Status: KYCExempt}
+		}
+		policy := g.config.kycPolicy(kyc.Status)
+
+		funding := allocation.Funds
+		if funding > 0 && !policy.Funding {
+			if err := g.blockByKYC(genesis, name, kyc, KYCBlockedFunding, "", funding); err != nil {
+				return err
+			}
+			funding = 0
+		}
+		funds := quantity.NewFromUint64(funding)
 
 		// initialize account
-		err := genesis.AddAccount(entityAddress, funds)
+		var err error
+		if exists {
+			err = genesis.AddBalance(entityAddress, funds)
+		} else {
+			err = genesis.AddAccount(entityAddress, funds)
+		}
 		if err != nil {
 			return err
 		}
 
 		// Ensure that we don't self stake if we have less than the minimum
 		// balance. Skip this entity
-		if funds.Cmp(quantity.NewFromUint64(g.config.MinimumBalance)) > 0 {
+		if funding > g.config.MinimumBalance {
 			// Clone because of potentially odd mutability bugs
 			escrowBalance := funds.Clone()
 
 			// subtract minimum_balance on the all
 			escrowBalance.Sub(quantity.NewFromUint64(g.config.MinimumBalance))
 
-			// Stake to self
-			err = genesis.AddDelegation(entityAddress, entityAddress, escrowBalance)
+			// Stake to self, unless KYC does not allow it. The funds stay
+			// in the general balance of the entity then.
+			if policy.SelfStake {
+				err = delegate(entityAddress, entityAddress, escrowBalance)
+			} else {
+				g.report.KYC = append(g.report.KYC, &KYCBlock{
+					Entity:     name,
+					KYCRecord:  *kyc,
+					Allocation: KYCBlockedSelfStake,
+					Amount:     funding - g.config.MinimumBalance,
+				})
+			}
 			if err != nil {
 				return err
 			}
 		}
 
-		err = g.setupEntityDelegations(genesis, entityAddress, allocation.Delegations)
+		err = g.setupEntityDelegations(genesis, delegate, name, entityAddress, kyc, policy, allocation.Delegations)
 		if err != nil {
 			return err
 		}
 	}
+	g.report.KYC.sort()
 	return nil
 }
 
-func (g *genesisCreator) setupEntityDelegations(genesis *AccountingGenesis, delegateAddress staking.Address, delegations map[string]uint64) error {
+func (g *genesisCreator) setupEntityDelegations(
+	genesis *AccountingGenesis,
+	delegate func(from, to staking.Address, amount *quantity.Quantity) error,
+	name string,
+	delegateAddress staking.Address,
+	kyc *KYCRecord,
+	policy KYCPolicy,
+	delegations map[string]uint64,
+) error {
 	for accountName, amount := range delegations {
 		account, ok := g.config.Accounts[accountName]
 		if !ok {
@@ -392,7 +685,13 @@ func (g *genesisCreator) setupEntityDelegations(genesis *AccountingGenesis, dele
 		if amount == 0 {
 			continue
 		}
-		err := genesis.AddDelegation(account.address, delegateAddress, quantity.NewFromUint64(amount))
+		if !policy.Delegations {
+			if err := g.blockByKYC(genesis, name, kyc, KYCBlockedDelegation, accountName, amount); err != nil {
+				return err
+			}
+			continue
+		}
+		err := delegate(account.address, delegateAddress, quantity.NewFromUint64(amount))
 		if err != nil {
 			return err
 		}
@@ -400,6 +699,31 @@ func (g *genesisCreator) setupEntityDelegations(genesis *AccountingGenesis, dele
 	return nil
 }
 
+// blockByKYC reports an allocation blocked by KYC and redirects its amount to
+// the redirect account, if there is one. Funding is taken from the common
+// pool, a delegation from its delegating account.
+func (g *genesisCreator) blockByKYC(genesis *AccountingGenesis, name string, kyc *KYCRecord, allocation, accountName string, amount uint64) error {
+	block := &KYCBlock{
+		Entity:     name,
+		KYCRecord:  *kyc,
+		Allocation: allocation,
+		Account:    accountName,
+		Amount:     amount,
+	}
+	g.report.KYC = append(g.report.KYC, block)
+
+	if g.config.KYCRedirectAccount == "" {
+		return nil
+	}
+	block.RedirectedTo = strings.ToLower(g.config.KYCRedirectAccount)
+	redirect := g.config.Accounts[block.RedirectedTo]
+
+	if allocation == KYCBlockedFunding {
+		return genesis.AddBalance(redirect.address, quantity.NewFromUint64(amount))
+	}
+	return genesis.TransferBalance(g.config.Accounts[accountName].address, redirect.address, quantity.NewFromUint64(amount))
+}
+
 // Ledger returns the created ledger
 func (g *genesisCreator) generateAccountingGenesis() (*staking.Genesis, error) {
 	// Start by adding the defined accounts in the genesis allocations document
@@ -408,6 +732,12 @@ func (g *genesisCreator) generateAccountingGenesis() (*staking.Genesis, error) {
 		return nil, err
 	}
 
+	// Two packages with the same entity key would map to the same account,
+	// report every such package before touching the ledger.
+	if err = checkDuplicateEntityIDs(g.options.Entities.All()); err != nil {
+		return nil, err
+	}
+
 	// Setup entity mappings
 	for name, entity := range g.options.Entities.All() {
 		address := staking.NewAddress(entity.ID)
@@ -417,7 +747,9 @@ func (g *genesisCreator) generateAccountingGenesis() (*staking.Genesis, error) {
 		}
 		logger.Info(`adding entity name and address mapping`,
 			"entity_name", name, "address", addressTxt)
-		g.addEntityMapping(name, address)
+		if err = g.addEntityMapping(name, address); err != nil {
+			return nil, err
+		}
 	}
 
 	err = g.setupAccountsForEntities(genesis, g.entityAllocationTable.All())
@@ -434,8 +766,17 @@ func (g *genesisCreator) generateAccountingGenesis() (*staking.Genesis, error) {
 
 	//g.processAccountDelegations(genesis)
 
-	partial := genesis.GetPartialGenesis()
-	return &partial, nil
+	if g.adjustments != nil {
+		names, err := g.accountNames()
+		if err != nil {
+			return nil, err
+		}
+		if g.report.Adjustments, err = ApplyAdjustments(genesis, g.adjustments, names); err != nil {
+			return nil, err
+		}
+	}
+
+	return genesis.GetPartialGenesis()
 }
 
 func (g *genesisCreator) GenerateGenesis() (*staking.Genesis, error) {
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
@@ -291,3 +293,155 @@ func TestLoadStakingParameters(t *testing.T) {
 
 	require.Equal(t, params.Thresholds[staking.KindEntity], *quantity.NewFromUint64(100_000_000_000))
 }
+
+func TestGenerateStakingLedgerRequireGithubHandles(t *testing.T) {
+	options := genericGenesisOptions([]string{
+		"test1",
+		"test2",
+		"test3",
+		"test4",
+	})
+	options.ConfigurationPath = "fixtures/staking_ledger_config.yaml"
+	options.AllocationsPath = "fixtures/allocations.csv"
+	options.RequireGithubHandles = true
+	_, err := stakinggenesis.Create(options)
+	require.NoError(t, err)
+
+	options.Entities = MakeFakeEntities([]string{
+		"test1",
+		"test2",
+		"test3",
+		"test4",
+		"unknown",
+	})
+	_, err = stakinggenesis.Create(options)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown")
+}
+
+func TestGenerateStakingLedgerDuplicateEntityIDs(t *testing.T) {
+	entities := MakeFakeEntities([]string{
+		"test1",
+		"test2",
+		"test3",
+		"test4",
+	})
+	entities.entities["test4"] = entities.entities["test2"]
+
+	options := genericGenesisOptions(nil)
+	options.Entities = entities
+	options.ConfigurationPath = "fixtures/staking_ledger_config.yaml"
+	options.AllocationsPath = "fixtures/allocations.csv"
+	_, err := stakinggenesis.Create(options)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "shared by packages test2, test4")
+}
+
+func TestGenerateStakingLedgerFromBaseGenesis(t *testing.T) {
+	entities := MakeFakeEntities([]string{
+		"test1",
+		"test2",
+		"test3",
+		"test4",
+		"test5",
+	})
+	options := genericGenesisOptions(nil)
+	options.Entities = entities
+	options.ConfigurationPath = "fixtures/staking_ledger_config.yaml"
+	options.AllocationsPath = "fixtures/allocations.csv"
+	options.IsTestGenesis = true
+	expected, err := stakinggenesis.Create(options)
+	require.NoError(t, err)
+
+	// Only the test only entity is added on top of the base, the
+	// allocations are already in it.
+	options.IsTestGenesis = false
+	base, err := stakinggenesis.Create(options)
+	require.NoError(t, err)
+	baseCommonPool := base.CommonPool.Clone()
+
+	options.IsTestGenesis = true
+	options.BaseGenesis = base
+	options.AllocationsPath = writeAllocations(t, "")
+	genesis, err := stakinggenesis.Create(options)
+	require.NoError(t, err)
+	require.Equal(t, expected, genesis)
+	require.Equal(t, *baseCommonPool, base.CommonPool, "the base genesis must not be modified")
+
+	base.TokenValueExponent = 6
+	_, err = stakinggenesis.Create(options)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match the configured ROSE")
+	base.TokenValueExponent = 9
+
+	// A configuration written for another total supply is rejected.
+	require.NoError(t, base.TotalSupply.Add(quantity.NewFromUint64(1)))
+	_, err = stakinggenesis.Create(options)
+	require.EqualError(t, err,
+		"base genesis total supply 10000000000000000001 does not match the configured total supply 10000000000000000000 (10000000000 tokens)")
+}
+
+// writeAllocations writes an allocations table with the header of the
+// allocations fixture and returns its path.
+func writeAllocations(t *testing.T, rows string) string {
+	b, err := ioutil.ReadFile("fixtures/allocations.csv")
+	require.NoError(t, err)
+	header := strings.SplitAfterN(string(b), "\n", 2)[0]
+
+	dir, err := ioutil.TempDir("", "allocations")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "allocations.csv")
+	require.NoError(t, ioutil.WriteFile(path, []byte(header+rows), 0o644))
+	return path
+}
+
+func TestGenerateStakingLedgerBaseGenesisDelta(t *testing.T) {
+	entities := MakeFakeEntities([]string{
+		"test1",
+		"test2",
+		"test3",
+		"test4",
+	})
+	options := genericGenesisOptions(nil)
+	options.Entities = entities
+	options.ConfigurationPath = "fixtures/staking_ledger_config.yaml"
+	options.AllocationsPath = "fixtures/allocations.csv"
+	base, err := stakinggenesis.Create(options)
+	require.NoError(t, err)
+
+	// The allocation of an entity of the base genesis is added to its
+	// account and to the delegations to it.
+	options.BaseGenesis = base
+	options.AllocationsPath = writeAllocations(t, `Test2,test2,test2,TRUE,TRUE,"1,000",500,0,0%,10%`+"\n")
+	genesis, err := stakinggenesis.Create(options)
+	require.NoError(t, err)
+
+	tokens := func(n uint64) *quantity.Quantity {
+		q := quantity.NewFromUint64(n)
+		require.NoError(t, q.Mul(quantity.NewFromUint64(1_000_000_000)))
+		return q
+	}
+	added := func(before quantity.Quantity, amount *quantity.Quantity) quantity.Quantity {
+		q := before.Clone()
+		require.NoError(t, q.Add(amount))
+		return *q
+	}
+	removed := func(before quantity.Quantity, amount *quantity.Quantity) quantity.Quantity {
+		q := before.Clone()
+		require.NoError(t, q.Sub(amount))
+		return *q
+	}
+
+	test2 := staking.NewAddress(entities.ResolveEntity("test2").ID)
+	var account1 staking.Address
+	require.NoError(t, account1.UnmarshalText([]byte("oasis1qz2kz3zkgf6trclyajtyg4jecw7es7p5tutfqaz0")))
+
+	// The funding above the minimum balance is staked to self.
+	require.Equal(t, added(base.Ledger[test2].General.Balance, tokens(100)), genesis.Ledger[test2].General.Balance)
+	require.Equal(t, added(base.Ledger[test2].Escrow.Active.Balance, tokens(1400)), genesis.Ledger[test2].Escrow.Active.Balance)
+	require.Equal(t, removed(base.Ledger[account1].General.Balance, tokens(500)), genesis.Ledger[account1].General.Balance)
+	require.Equal(t, added(base.Delegations[test2][account1].Shares, tokens(500)), genesis.Delegations[test2][account1].Shares)
+	require.Equal(t, added(base.Delegations[test2][test2].Shares, tokens(900)), genesis.Delegations[test2][test2].Shares)
+	require.Equal(t, removed(base.CommonPool, tokens(1000)), genesis.CommonPool)
+}
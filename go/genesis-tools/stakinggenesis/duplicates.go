@@ -0,0 +1,123 @@
+package stakinggenesis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+)
+
+// Kinds of keys that must be unique across entity packages.
+const (
+	KeyKindEntity        = "entity ID"
+	KeyKindNode          = "node ID"
+	KeyKindNodeConsensus = "node consensus key"
+	KeyKindNodeP2P       = "node P2P key"
+	KeyKindNodeTLS       = "node TLS key"
+)
+
+// KeyConflict is a public key that is shared by more than one entity
+// package.
+type KeyConflict struct {
+	Kind     string
+	Key      signature.PublicKey
+	Packages []string
+}
+
+func (c KeyConflict) String() string {
+	return fmt.Sprintf("%s %s is shared by packages %s", c.Kind, c.Key, strings.Join(c.Packages, ", "))
+}
+
+// DuplicateKeysError is returned when keys are shared between entity
+// packages. Every conflict is reported.
+type DuplicateKeysError struct {
+	Conflicts []KeyConflict
+}
+
+func (e *DuplicateKeysError) Error() string {
+	conflicts := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		conflicts = append(conflicts, conflict.String())
+	}
+	return fmt.Sprintf("duplicate keys found in entity packages: %s", strings.Join(conflicts, "; "))
+}
+
+type keyUsage struct {
+	kind string
+	key  signature.PublicKey
+}
+
+// keyIndex tracks which packages use which keys.
+type keyIndex map[keyUsage][]string
+
+func (k keyIndex) add(kind string, key signature.PublicKey, name string) {
+	// Unset keys (e.g. an absent next TLS key) are never conflicts.
+	if key == (signature.PublicKey{}) {
+		return
+	}
+	usage := keyUsage{kind: kind, key: key}
+	for _, existing := range k[usage] {
+		if existing == name {
+			return
+		}
+	}
+	k[usage] = append(k[usage], name)
+}
+
+// err returns a *DuplicateKeysError for all keys used by more than one
+// package, or nil. Conflicts are sorted by kind and key.
+func (k keyIndex) err() error {
+	var conflicts []KeyConflict
+	for usage, names := range k {
+		if len(names) < 2 {
+			continue
+		}
+		sorted := append([]string{}, names...)
+		sort.Strings(sorted)
+		conflicts = append(conflicts, KeyConflict{
+			Kind:     usage.kind,
+			Key:      usage.key,
+			Packages: sorted,
+		})
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return conflicts[i].Kind < conflicts[j].Kind
+		}
+		return conflicts[i].Key.String() < conflicts[j].Key.String()
+	})
+	return &DuplicateKeysError{Conflicts: conflicts}
+}
+
+// CheckDuplicateKeys ensures that no entity public key, node ID, or node
+// consensus, P2P or TLS key is used by more than one entity package.
+func CheckDuplicateKeys(packages []*EntityPackage) error {
+	index := make(keyIndex)
+	for _, pkg := range packages {
+		index.add(KeyKindEntity, pkg.Entity.ID, pkg.Name)
+		if pkg.Node == nil {
+			continue
+		}
+		index.add(KeyKindNode, pkg.Node.ID, pkg.Name)
+		index.add(KeyKindNodeConsensus, pkg.Node.Consensus.ID, pkg.Name)
+		index.add(KeyKindNodeP2P, pkg.Node.P2P.ID, pkg.Name)
+		index.add(KeyKindNodeTLS, pkg.Node.TLS.PubKey, pkg.Name)
+		index.add(KeyKindNodeTLS, pkg.Node.TLS.NextPubKey, pkg.Name)
+	}
+	return index.err()
+}
+
+// checkDuplicateEntityIDs ensures that no entity public key is used by more
+// than one of the named entities.
+func checkDuplicateEntityIDs(entities map[string]*entity.Entity) error {
+	index := make(keyIndex)
+	for name, ent := range entities {
+		index.add(KeyKindEntity, ent.ID, name)
+	}
+	return index.err()
+}
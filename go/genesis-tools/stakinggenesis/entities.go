@@ -6,10 +6,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/oasisprotocol/oasis-core/go/common/entity"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/common/quantity"
 	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
 )
@@ -28,19 +31,67 @@ type Entities interface {
 	ResolveEntity(name string) *entity.Entity
 }
 
+// EntityPackage is a loaded and verified entity package.
+type EntityPackage struct {
+	// Name is the normalized name of the package.
+	Name string
+	// Path is the path of the unpacked package directory.
+	Path string
+
+	Entity       *entity.Entity
+	SignedEntity *entity.SignedEntity
+
+	// Node and SignedNode are nil if the package has no node descriptor.
+	Node       *node.Node
+	SignedNode *node.MultiSignedNode
+}
+
+// EntitiesDirectoryOptions options for loading an EntitiesDirectory.
+type EntitiesDirectoryOptions struct {
+	// NamePolicy is the naming policy enforced on package directory names.
+	NamePolicy PackageNamePolicy
+	// Versions are the accepted descriptor versions. Nil disables the
+	// version check.
+	Versions *DescriptorVersions
+	// Workers is the number of packages loaded concurrently, defaults to the
+	// number of CPUs.
+	Workers int
+	// SkipInvalid excludes packages that fail to load instead of failing
+	// the whole load. The failures are available from Excluded.
+	SkipInvalid bool
+}
+
 // EntitiesDirectory is a set of directories of unpacked entities packages.
 type EntitiesDirectory struct {
-	paths []string
+	paths   []string
+	options EntitiesDirectoryOptions
 
 	// A map of Entity Names to the Entity object
 	entities map[string]*entity.Entity
+	packages []*EntityPackage
+	excluded LoadErrors
 }
 
-// LoadEntitiesDirectory loads a directory of unpacked entity packages.
+// LoadEntitiesDirectory loads a directory of unpacked entity packages using
+// the default options.
 func LoadEntitiesDirectory(dirPaths []string) (*EntitiesDirectory, error) {
-	dir := &EntitiesDirectory{paths: dirPaths}
+	return LoadEntitiesDirectoryWithOptions(dirPaths, EntitiesDirectoryOptions{
+		NamePolicy: DefaultPackageNamePolicy(),
+		Versions:   DefaultDescriptorVersions(),
+	})
+}
 
-	dir.Load()
+// LoadEntitiesDirectoryWithOptions loads a directory of unpacked entity
+// packages.
+func LoadEntitiesDirectoryWithOptions(dirPaths []string, options EntitiesDirectoryOptions) (*EntitiesDirectory, error) {
+	dir := &EntitiesDirectory{
+		paths:   dirPaths,
+		options: options,
+	}
+
+	if err := dir.Load(); err != nil {
+		return nil, err
+	}
 
 	return dir, nil
 }
@@ -57,6 +108,17 @@ func (e *EntitiesDirectory) All() map[string]*entity.Entity {
 	return e.entities
 }
 
+// Packages returns all of the loaded entity packages in load order.
+func (e *EntitiesDirectory) Packages() []*EntityPackage {
+	return e.packages
+}
+
+// Excluded returns the failures of the packages that were skipped because of
+// the SkipInvalid option.
+func (e *EntitiesDirectory) Excluded() LoadErrors {
+	return e.excluded
+}
+
 func (e *EntitiesDirectory) ResolveEntity(name string) *entity.Entity {
 	ent, ok := e.entities[name]
 	if !ok {
@@ -67,62 +129,234 @@ func (e *EntitiesDirectory) ResolveEntity(name string) *entity.Entity {
 
 // Load loads a directory of entities. This should a directory of unpacked
 // entity packages.
+// Package names are validated against the configured naming policy and must
+// be unique when case-folded, across all of the directories. Entity and node
+// keys must not be shared between packages.
+//
+// Packages are loaded and verified by a bounded pool of workers. The load
+// order, and so the order of Packages, is deterministic: directories in the
+// order given, packages in lexicographical order. Every failure is reported
+// as part of LoadErrors.
 func (e *EntitiesDirectory) Load() error {
 	e.entities = make(map[string]*entity.Entity)
+	e.packages = nil
+	e.excluded = nil
+	names := make(packageNameSet)
 
+	var errs LoadErrors
+	var jobs []packageJob
 	for _, dirPath := range e.paths {
-		err := e.loadDir(dirPath)
-		if err != nil {
-			return err
+		dirJobs, dirErrs := e.listDir(dirPath, names)
+		jobs = append(jobs, dirJobs...)
+		errs = append(errs, dirErrs...)
+	}
+
+	var loaded []*EntityPackage
+	packages, pkgErrs := e.loadPackages(jobs)
+	for i, pkg := range packages {
+		if pkgErrs[i] != nil {
+			errs = append(errs, pkgErrs[i])
+			continue
 		}
+		loaded = append(loaded, pkg)
 	}
+
+	errs = append(errs, names.packageErrors()...)
+	errs = append(errs, duplicateKeyErrors(loaded)...)
+
+	if len(errs) > 0 && (!e.options.SkipInvalid || len(errs.Fatal()) > 0) {
+		return errs
+	}
+
+	// Only keep the packages without any errors.
+	invalid := make(map[string]bool)
+	for _, name := range errs.Names() {
+		invalid[name] = true
+	}
+	for _, pkg := range loaded {
+		if invalid[pkg.Name] {
+			continue
+		}
+		e.entities[pkg.Name] = pkg.Entity
+		e.packages = append(e.packages, pkg)
+	}
+	e.excluded = errs
+
 	return nil
 }
 
-func (e *EntitiesDirectory) loadDir(dirPath string) error {
+// packageJob is an unpacked entity package waiting to be loaded.
+type packageJob struct {
+	dirPath string
+	name    string
+}
+
+func (j packageJob) path() string {
+	return path.Join(j.dirPath, j.name)
+}
+
+func (j packageJob) error(category LoadErrorCategory, err error) *PackageError {
+	return &PackageError{
+		Name:     strings.ToLower(j.name),
+		Path:     j.path(),
+		Category: category,
+		Err:      err,
+	}
+}
+
+// listDir returns the packages in a directory that follow the naming policy.
+func (e *EntitiesDirectory) listDir(dirPath string, names packageNameSet) ([]packageJob, LoadErrors) {
 	files, err := ioutil.ReadDir(dirPath)
 	if err != nil {
-		logger.Error("failed to load the entities directory",
-			"err", err,
-		)
+		return nil, LoadErrors{{
+			Path:     dirPath,
+			Category: CategoryDirectory,
+			Err:      err,
+		}}
 	}
+
+	var jobs []packageJob
+	var errs LoadErrors
 	for _, fileInfo := range files {
 		// Only process directories.
 		if !fileInfo.IsDir() {
 			continue
 		}
-		entityName := fileInfo.Name()
-		ent, err := e.loadEntityDir(dirPath, entityName)
-		if err != nil {
-			return err
+		job := packageJob{dirPath: dirPath, name: fileInfo.Name()}
+		if err = e.options.NamePolicy.ValidateName(job.name); err != nil {
+			errs = append(errs, job.error(CategoryName, err))
+			continue
 		}
+		names.add(job.name, job.path())
+		jobs = append(jobs, job)
+	}
+	return jobs, errs
+}
 
-		e.entities[strings.ToLower(entityName)] = ent
+// loadPackages loads and verifies the packages using up to
+// options.Workers goroutines. The results are in the order of the jobs.
+func (e *EntitiesDirectory) loadPackages(jobs []packageJob) ([]*EntityPackage, []*PackageError) {
+	workers := e.options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
 	}
-	return nil
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	packages := make([]*EntityPackage, len(jobs))
+	errs := make([]*PackageError, len(jobs))
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				packages[index], errs[index] = e.loadEntityDir(jobs[index])
+			}
+		}()
+	}
+	for index := range jobs {
+		indices <- index
+	}
+	close(indices)
+	wg.Wait()
+
+	return packages, errs
+}
+
+// duplicateKeyErrors returns an error for every package involved in a key
+// conflict.
+func duplicateKeyErrors(packages []*EntityPackage) LoadErrors {
+	err := CheckDuplicateKeys(packages)
+	if err == nil {
+		return nil
+	}
+
+	paths := make(map[string]string)
+	for _, pkg := range packages {
+		paths[pkg.Name] = pkg.Path
+	}
+
+	var errs LoadErrors
+	for _, conflict := range err.(*DuplicateKeysError).Conflicts {
+		for _, name := range conflict.Packages {
+			errs = append(errs, &PackageError{
+				Name:     name,
+				Path:     paths[name],
+				Category: CategoryDuplicate,
+				Err:      &DuplicateKeysError{Conflicts: []KeyConflict{conflict}},
+			})
+		}
+	}
+	return errs
 }
 
-func (e *EntitiesDirectory) loadEntityDir(dirPath string, entityName string) (*entity.Entity, error) {
-	entityGenesisPath := path.Join(dirPath, entityName, "entity/entity_genesis.json")
+func (e *EntitiesDirectory) loadEntityDir(job packageJob) (*EntityPackage, *PackageError) {
+	entityGenesisPath := path.Join(job.path(), "entity/entity_genesis.json")
 	logger.Debug("loading entity directory", "dir", entityGenesisPath)
 	if !isFile(entityGenesisPath) {
-		return nil, fmt.Errorf("Entity for \"%s\" does not exist", entityName)
+		return nil, job.error(CategoryMissing, fmt.Errorf("Entity for \"%s\" does not exist", job.name))
 	}
 
 	b, err := ioutil.ReadFile(entityGenesisPath)
 	if err != nil {
-		return nil, err
+		return nil, job.error(CategoryMalformed, err)
 	}
 
 	var signedEntity entity.SignedEntity
 	if err = json.Unmarshal(b, &signedEntity); err != nil {
-		return nil, err
+		return nil, job.error(CategoryMalformed, fmt.Errorf("entity descriptor: %w", err))
 	}
 
 	var ent entity.Entity
-	if err := signedEntity.Open(registry.RegisterGenesisEntitySignatureContext, &ent); err != nil {
-		return nil, err
+	if err = signedEntity.Open(registry.RegisterGenesisEntitySignatureContext, &ent); err != nil {
+		return nil, job.error(CategorySignature, fmt.Errorf("entity descriptor: %w", err))
+	}
+	if e.options.Versions != nil {
+		if err = e.options.Versions.CheckEntity(job.name, &ent); err != nil {
+			return nil, job.error(CategoryVersion, err)
+		}
+	}
+
+	pkg := &EntityPackage{
+		Name:         strings.ToLower(job.name),
+		Path:         job.path(),
+		Entity:       &ent,
+		SignedEntity: &signedEntity,
+	}
+
+	// The node descriptor is optional, entities may be registered without
+	// any nodes.
+	nodeGenesisPath := path.Join(job.path(), "node/node_genesis.json")
+	if !isFile(nodeGenesisPath) {
+		return pkg, nil
+	}
+
+	b, err = ioutil.ReadFile(nodeGenesisPath)
+	if err != nil {
+		return nil, job.error(CategoryMalformed, err)
 	}
 
-	return &ent, nil
+	var signedNode node.MultiSignedNode
+	if err = json.Unmarshal(b, &signedNode); err != nil {
+		return nil, job.error(CategoryMalformed, fmt.Errorf("node descriptor: %w", err))
+	}
+
+	var n node.Node
+	if err = signedNode.Open(registry.RegisterGenesisNodeSignatureContext, &n); err != nil {
+		return nil, job.error(CategorySignature, fmt.Errorf("node descriptor: %w", err))
+	}
+	if e.options.Versions != nil {
+		if err = e.options.Versions.CheckNode(job.name, &n); err != nil {
+			return nil, job.error(CategoryVersion, err)
+		}
+	}
+
+	pkg.Node = &n
+	pkg.SignedNode = &signedNode
+
+	return pkg, nil
 }
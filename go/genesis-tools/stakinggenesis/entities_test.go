@@ -0,0 +1,403 @@
+package stakinggenesis_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// testPackage describes an unpacked entity package written by tests. All
+// keys are derived from the seeds so packages are deterministic.
+type testPackage struct {
+	name string
+	seed string
+	// nodeSeed is the seed of the node keys, defaults to seed.
+	nodeSeed    string
+	withoutNode bool
+
+	entityFn func(*entity.Entity)
+	nodeFn   func(*node.Node)
+}
+
+func (p testPackage) write(t testing.TB, dirPath string) *entity.Entity {
+	entitySigner := memorySigner.NewTestSigner(p.seed)
+	ent := &entity.Entity{
+		Versioned: cbor.NewVersioned(entity.LatestEntityDescriptorVersion),
+		ID:        entitySigner.Public(),
+	}
+
+	var signedNode *node.MultiSignedNode
+	if !p.withoutNode {
+		nodeSeed := p.nodeSeed
+		if nodeSeed == "" {
+			nodeSeed = p.seed
+		}
+		nodeSigner := memorySigner.NewTestSigner(nodeSeed + "/node")
+		consensusSigner := memorySigner.NewTestSigner(nodeSeed + "/consensus")
+		p2pSigner := memorySigner.NewTestSigner(nodeSeed + "/p2p")
+		tlsSigner := memorySigner.NewTestSigner(nodeSeed + "/tls")
+
+		var consensusAddress node.ConsensusAddress
+		require.NoError(t, consensusAddress.UnmarshalText([]byte(p2pSigner.Public().String()+"@1.2.3.4:26656")))
+
+		n := &node.Node{
+			Versioned: cbor.NewVersioned(node.LatestNodeDescriptorVersion),
+			ID:        nodeSigner.Public(),
+			EntityID:  ent.ID,
+			TLS: node.TLSInfo{
+				PubKey: tlsSigner.Public(),
+			},
+			P2P: node.P2PInfo{
+				ID: p2pSigner.Public(),
+			},
+			Consensus: node.ConsensusInfo{
+				ID:        consensusSigner.Public(),
+				Addresses: []node.ConsensusAddress{consensusAddress},
+			},
+			Roles: node.RoleValidator,
+		}
+		if p.nodeFn != nil {
+			p.nodeFn(n)
+		}
+		ent.Nodes = []signature.PublicKey{n.ID}
+
+		var err error
+		signedNode, err = node.MultiSignNode(
+			[]signature.Signer{nodeSigner, consensusSigner, p2pSigner, tlsSigner},
+			registry.RegisterGenesisNodeSignatureContext,
+			n,
+		)
+		require.NoError(t, err)
+	}
+	if p.entityFn != nil {
+		p.entityFn(ent)
+	}
+
+	signedEntity, err := entity.SignEntity(entitySigner, registry.RegisterGenesisEntitySignatureContext, ent)
+	require.NoError(t, err)
+
+	writeJSON(t, filepath.Join(dirPath, p.name, "entity", "entity_genesis.json"), signedEntity)
+	if signedNode != nil {
+		writeJSON(t, filepath.Join(dirPath, p.name, "node", "node_genesis.json"), signedNode)
+	}
+	return ent
+}
+
+// writeEntityPackage writes an unpacked entity package named name into
+// dirPath. The entity key is derived from seed.
+func writeEntityPackage(t testing.TB, dirPath, name, seed string) *entity.Entity {
+	return testPackage{name: name, seed: seed}.write(t, dirPath)
+}
+
+func writeJSON(t testing.TB, filePath string, v interface{}) {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0o755))
+	require.NoError(t, ioutil.WriteFile(filePath, b, 0o644))
+}
+
+func tempDir(t testing.TB) string {
+	dir, err := ioutil.TempDir("", "entities")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestLoadEntitiesDirectory(t *testing.T) {
+	dir := tempDir(t)
+	ent := writeEntityPackage(t, dir, "Test1", "test1")
+	writeEntityPackage(t, dir, "test2", "test2")
+
+	entities, err := stakinggenesis.LoadEntitiesDirectory([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, entities.All(), 2)
+	require.Equal(t, ent.ID, entities.ResolveEntity("test1").ID)
+}
+
+func TestLoadEntitiesDirectoryCaseInsensitiveDuplicates(t *testing.T) {
+	dir1 := tempDir(t)
+	dir2 := tempDir(t)
+	writeEntityPackage(t, dir1, "test1", "test1")
+	writeEntityPackage(t, dir2, "TEST1", "test1-other")
+
+	_, err := stakinggenesis.LoadEntitiesDirectory([]string{dir1, dir2})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `duplicate entity package names: "test1"`)
+}
+
+func TestLoadEntitiesDirectoryInvalidName(t *testing.T) {
+	dir := tempDir(t)
+	writeEntityPackage(t, dir, "bad name", "test1")
+
+	_, err := stakinggenesis.LoadEntitiesDirectory([]string{dir})
+	require.Error(t, err)
+}
+
+func TestLoadEntitiesDirectoryNodes(t *testing.T) {
+	dir := tempDir(t)
+	writeEntityPackage(t, dir, "test1", "test1")
+	testPackage{name: "test2", seed: "test2", withoutNode: true}.write(t, dir)
+
+	entities, err := stakinggenesis.LoadEntitiesDirectory([]string{dir})
+	require.NoError(t, err)
+
+	packages := entities.Packages()
+	require.Len(t, packages, 2)
+	require.NotNil(t, packages[0].Node)
+	require.Equal(t, packages[0].Entity.ID, packages[0].Node.EntityID)
+	require.Nil(t, packages[1].Node)
+}
+
+func TestLoadEntitiesDirectoryDuplicateKeys(t *testing.T) {
+	dir := tempDir(t)
+	writeEntityPackage(t, dir, "test1", "shared")
+	writeEntityPackage(t, dir, "test2", "shared")
+	testPackage{name: "test3", seed: "test3", nodeSeed: "shared"}.write(t, dir)
+	writeEntityPackage(t, dir, "test4", "test4")
+
+	_, err := stakinggenesis.LoadEntitiesDirectory([]string{dir})
+	require.Error(t, err)
+
+	var loadErrs stakinggenesis.LoadErrors
+	require.True(t, errors.As(err, &loadErrs))
+
+	conflicts := make(map[string][]string)
+	for _, loadErr := range loadErrs {
+		require.Equal(t, stakinggenesis.CategoryDuplicate, loadErr.Category)
+
+		var duplicates *stakinggenesis.DuplicateKeysError
+		require.True(t, errors.As(loadErr, &duplicates))
+		for _, conflict := range duplicates.Conflicts {
+			conflicts[conflict.Kind] = conflict.Packages
+		}
+	}
+	require.Equal(t, map[string][]string{
+		stakinggenesis.KeyKindEntity:        {"test1", "test2"},
+		stakinggenesis.KeyKindNode:          {"test1", "test2", "test3"},
+		stakinggenesis.KeyKindNodeConsensus: {"test1", "test2", "test3"},
+		stakinggenesis.KeyKindNodeP2P:       {"test1", "test2", "test3"},
+		stakinggenesis.KeyKindNodeTLS:       {"test1", "test2", "test3"},
+	}, conflicts)
+}
+
+func TestLoadEntitiesDirectoryDescriptorVersions(t *testing.T) {
+	for _, tc := range []struct {
+		pkg        testPackage
+		descriptor string
+		version    uint16
+	}{
+		{
+			pkg: testPackage{name: "test1", seed: "test1", entityFn: func(e *entity.Entity) {
+				e.Versioned = cbor.NewVersioned(0)
+			}},
+			descriptor: "entity",
+			version:    0,
+		},
+		{
+			pkg: testPackage{name: "test1", seed: "test1", nodeFn: func(n *node.Node) {
+				n.Versioned = cbor.NewVersioned(node.LatestNodeDescriptorVersion + 1)
+			}},
+			descriptor: "node",
+			version:    node.LatestNodeDescriptorVersion + 1,
+		},
+	} {
+		dir := tempDir(t)
+		tc.pkg.write(t, dir)
+
+		_, err := stakinggenesis.LoadEntitiesDirectory([]string{dir})
+		var versionErr *stakinggenesis.DescriptorVersionError
+		require.True(t, errors.As(err, &versionErr), "expected a version error, got %v", err)
+		require.Equal(t, tc.descriptor, versionErr.Descriptor)
+		require.Equal(t, tc.version, versionErr.Version)
+		require.Contains(t, err.Error(), "oasis-node CLI from oasis-core "+stakinggenesis.TargetOasisCoreRelease)
+
+		// The check can be disabled.
+		_, err = stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{dir}, stakinggenesis.EntitiesDirectoryOptions{
+			NamePolicy: stakinggenesis.DefaultPackageNamePolicy(),
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestDefaultDescriptorVersions(t *testing.T) {
+	// The bounds agree with the version checks of oasis-core.
+	versions := stakinggenesis.DefaultDescriptorVersions()
+	for v := uint16(0); v <= entity.LatestEntityDescriptorVersion+1; v++ {
+		ent := entity.Entity{Versioned: cbor.NewVersioned(v)}
+		require.Equal(t, ent.ValidateBasic(false) == nil, versions.CheckEntity("test", &ent) == nil, "entity version %d", v)
+	}
+	for v := uint16(0); v <= node.LatestNodeDescriptorVersion+1; v++ {
+		n := node.Node{Versioned: cbor.NewVersioned(v)}
+		require.Equal(t, n.ValidateBasic(false) == nil, versions.CheckNode("test", &n) == nil, "node version %d", v)
+	}
+	require.EqualValues(t, entity.LatestEntityDescriptorVersion, versions.MaxEntity)
+	require.EqualValues(t, node.LatestNodeDescriptorVersion, versions.MaxNode)
+}
+
+func TestLoadEntitiesDirectoryOrderAndErrors(t *testing.T) {
+	dir1 := tempDir(t)
+	dir2 := tempDir(t)
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("test%02d", i)
+		writeEntityPackage(t, dir1, name, name)
+	}
+	writeEntityPackage(t, dir2, "b", "b")
+	writeEntityPackage(t, dir2, "a", "a")
+
+	for _, workers := range []int{1, 4, 32} {
+		entities, err := stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{dir1, dir2}, stakinggenesis.EntitiesDirectoryOptions{
+			Workers: workers,
+		})
+		require.NoError(t, err)
+
+		var names []string
+		for _, pkg := range entities.Packages() {
+			names = append(names, pkg.Name)
+		}
+		require.Len(t, names, 22)
+		require.Equal(t, "test00", names[0])
+		require.Equal(t, "test19", names[19])
+		require.Equal(t, []string{"a", "b"}, names[20:])
+	}
+
+	// Every broken package is reported, not only the first.
+	require.NoError(t, os.Remove(filepath.Join(dir1, "test03", "entity", "entity_genesis.json")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir1, "test07", "node", "node_genesis.json"), []byte("not json"), 0o644))
+	_, err := stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{dir1, dir2}, stakinggenesis.EntitiesDirectoryOptions{
+		Workers: 4,
+	})
+	var loadErrs stakinggenesis.LoadErrors
+	require.True(t, errors.As(err, &loadErrs))
+	require.Len(t, loadErrs, 2)
+	require.Equal(t, "test03", loadErrs[0].Name)
+	require.Equal(t, filepath.Join(dir1, "test03"), loadErrs[0].Path)
+	require.Equal(t, stakinggenesis.CategoryMissing, loadErrs[0].Category)
+	require.Equal(t, "test07", loadErrs[1].Name)
+	require.Equal(t, stakinggenesis.CategoryMalformed, loadErrs[1].Category)
+}
+
+func TestLoadEntitiesDirectorySkipInvalid(t *testing.T) {
+	dir := tempDir(t)
+	writeEntityPackage(t, dir, "test1", "test1")
+	writeEntityPackage(t, dir, "test2", "shared")
+	writeEntityPackage(t, dir, "test3", "shared")
+	writeEntityPackage(t, dir, "test4", "test4")
+	writeEntityPackage(t, dir, "bad name", "bad")
+	require.NoError(t, os.Remove(filepath.Join(dir, "test4", "entity", "entity_genesis.json")))
+
+	options := stakinggenesis.EntitiesDirectoryOptions{
+		NamePolicy: stakinggenesis.DefaultPackageNamePolicy(),
+	}
+	_, err := stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{dir}, options)
+	require.Error(t, err)
+
+	options.SkipInvalid = true
+	entities, err := stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{dir}, options)
+	require.NoError(t, err)
+	require.Len(t, entities.All(), 1)
+	require.NotNil(t, entities.ResolveEntity("test1"))
+
+	excluded := entities.Excluded()
+	require.Equal(t, []string{"bad name", "test4", "test2", "test3"}, excluded.Names())
+
+	var out bytes.Buffer
+	require.NoError(t, excluded.WriteCSV(&out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, "name,path,category,reason", lines[0])
+	require.Len(t, lines, 1+len(excluded))
+
+	// Directory failures can't be skipped.
+	_, err = stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{dir, filepath.Join(dir, "missing")}, options)
+	require.Error(t, err)
+}
+
+func TestPackageNameFromFileName(t *testing.T) {
+	policy := stakinggenesis.DefaultPackageNamePolicy()
+
+	name, err := policy.PackageNameFromFileName("LRUki_LBL_UCL-entity.tar.gz")
+	require.NoError(t, err)
+	require.Equal(t, "LRUki_LBL_UCL", name)
+
+	for _, fileName := range []string{
+		"test1.tar.gz",
+		"test1-entity.tgz",
+		"-entity.tar.gz",
+		"../test1-entity.tar.gz",
+		"test 1-entity.tar.gz",
+	} {
+		_, err = policy.PackageNameFromFileName(fileName)
+		require.Error(t, err, fileName)
+	}
+}
+
+func TestValidatePackageFileNames(t *testing.T) {
+	policy := stakinggenesis.DefaultPackageNamePolicy()
+
+	require.NoError(t, policy.ValidatePackageFileNames([]string{
+		"test1-entity.tar.gz",
+		"test2-entity.tar.gz",
+	}))
+
+	err := policy.ValidatePackageFileNames([]string{
+		"test1-entity.tar.gz",
+		"Test1-entity.tar.gz",
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Test1-entity.tar.gz, test1-entity.tar.gz")
+}
+
+func TestCheckGithubHandles(t *testing.T) {
+	entities := MakeFakeEntities([]string{"test1", "test2"})
+
+	require.NoError(t, stakinggenesis.CheckGithubHandles(entities, map[string]bool{
+		"test1": true,
+		"test2": true,
+	}))
+
+	err := stakinggenesis.CheckGithubHandles(entities, map[string]bool{
+		"test1": true,
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "test2")
+}
+
+func BenchmarkLoadEntitiesDirectory(b *testing.B) {
+	dir := tempDir(b)
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("bench%03d", i)
+		writeEntityPackage(b, dir, name, name)
+	}
+
+	for _, workers := range []int{1, 0} {
+		name := "sequential"
+		if workers == 0 {
+			name = "parallel"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{dir}, stakinggenesis.EntitiesDirectoryOptions{
+					NamePolicy: stakinggenesis.DefaultPackageNamePolicy(),
+					Versions:   stakinggenesis.DefaultDescriptorVersions(),
+					Workers:    workers,
+				})
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package stakinggenesis
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LoadErrorCategory classifies why an entity package failed to load.
+type LoadErrorCategory string
+
+const (
+	// CategoryDirectory is an entities directory that could not be read.
+	CategoryDirectory LoadErrorCategory = "directory"
+	// CategoryName is a package name that breaks the naming policy.
+	CategoryName LoadErrorCategory = "name"
+	// CategoryMissing is a package without a required descriptor.
+	CategoryMissing LoadErrorCategory = "missing"
+	// CategoryMalformed is a descriptor that could not be read or decoded.
+	CategoryMalformed LoadErrorCategory = "malformed"
+	// CategorySignature is a descriptor with an invalid signature.
+	CategorySignature LoadErrorCategory = "signature"
+	// CategoryVersion is a descriptor with an unsupported version.
+	CategoryVersion LoadErrorCategory = "version"
+	// CategoryDuplicate is a package whose name or keys collide with
+	// another package.
+	CategoryDuplicate LoadErrorCategory = "duplicate"
+)
+
+// PackageError is a failure to load an entity package.
+type PackageError struct {
+	// Name is the normalized package name, empty for directory failures.
+	Name     string
+	Path     string
+	Category LoadErrorCategory
+	Err      error
+}
+
+func (e *PackageError) Error() string {
+	return fmt.Sprintf("%s [%s]: %s", e.Path, e.Category, e.Err)
+}
+
+func (e *PackageError) Unwrap() error {
+	return e.Err
+}
+
+// LoadErrors are all of the failures encountered while loading a set of
+// entity packages, in load order.
+type LoadErrors []*PackageError
+
+func (l LoadErrors) Error() string {
+	msgs := make([]string, 0, len(l))
+	for _, err := range l {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d entity package error(s): %s", len(l), strings.Join(msgs, "; "))
+}
+
+// As finds the first error in l that matches target.
+func (l LoadErrors) As(target interface{}) bool {
+	for _, err := range l {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether any error in l matches target.
+func (l LoadErrors) Is(target error) bool {
+	for _, err := range l {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fatal returns the errors that do not belong to a single package, and so
+// cannot be skipped by excluding the package.
+func (l LoadErrors) Fatal() LoadErrors {
+	var fatal LoadErrors
+	for _, err := range l {
+		if err.Name == "" {
+			fatal = append(fatal, err)
+		}
+	}
+	return fatal
+}
+
+// Names returns the distinct names of the packages that failed, in load
+// order.
+func (l LoadErrors) Names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, err := range l {
+		if err.Name == "" || seen[err.Name] {
+			continue
+		}
+		seen[err.Name] = true
+		names = append(names, err.Name)
+	}
+	return names
+}
+
+// WriteCSV writes the errors as a csv exclusion list.
+func (l LoadErrors) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"name", "path", "category", "reason"}); err != nil {
+		return err
+	}
+	for _, err := range l {
+		if werr := writer.Write([]string{err.Name, err.Path, string(err.Category), err.Err.Error()}); werr != nil {
+			return werr
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
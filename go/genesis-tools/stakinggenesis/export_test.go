@@ -0,0 +1,5 @@
+package stakinggenesis
+
+// ParseHumanReadableNumberToUint64 exposes parseHumanReadableNumberToUint64
+// to the tests.
+var ParseHumanReadableNumberToUint64 = parseHumanReadableNumberToUint64
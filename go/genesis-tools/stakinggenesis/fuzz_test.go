@@ -0,0 +1,78 @@
+//go:build go1.18
+// +build go1.18
+
+package stakinggenesis_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+)
+
+func FuzzAccountingGenesis(f *testing.F) {
+	op := func(code, from, to byte, amount uint16) []byte {
+		b := []byte{code, from, to, 0, 0}
+		binary.BigEndian.PutUint16(b[3:], amount)
+		return b
+	}
+	var seed []byte
+	seed = append(seed, op(0, 2, 0, 1000)...)
+	seed = append(seed, op(0, 3, 0, 500)...)
+	seed = append(seed, op(2, 2, 3, 300)...)
+	seed = append(seed, op(2, 3, 0, 77)...)
+	seed = append(seed, op(4, 2, 3, 100)...)
+	seed = append(seed, op(5, 3, 4, 50)...)
+	seed = append(seed, op(6, 3, 0, 0)...)
+	f.Add(seed, false)
+	f.Add(seed, true)
+
+	f.Fuzz(func(t *testing.T, ops []byte, seeded bool) {
+		runAccountingOps(t, propertyGenesis(t, seeded), ops)
+	})
+}
+
+func FuzzParseHumanReadableNumber(f *testing.F) {
+	for _, s := range []string{"0", "1,000", "18,446,744,073,709,551,615", "", "-1", "1.5", ",,"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		parsed, err := stakinggenesis.ParseHumanReadableNumberToUint64(s)
+		expected, expectedErr := strconv.ParseUint(strings.ReplaceAll(s, ",", ""), 10, 64)
+		if expectedErr != nil {
+			require.Error(t, err, s)
+			return
+		}
+		require.NoError(t, err, s)
+		require.Equal(t, expected, parsed, s)
+	})
+}
+
+func FuzzLoadEntityAllocationTable(f *testing.F) {
+	for _, path := range []string{"fixtures/allocations.csv", "fixtures/kyc_allocations.csv"} {
+		b, err := ioutil.ReadFile(path)
+		require.NoError(f, err)
+		f.Add(b)
+	}
+	f.Add([]byte{})
+	f.Add([]byte("Entity Name,Github handle,Entity Package Name,Entity Submitted,KYC Complete," +
+		"\"Total Rewards [sum of Quest + Grants, paid out from community & ecosystem]\",Account One,Account Two\n"))
+
+	config, err := stakinggenesis.LoadGenesisConfig(configFixturePath)
+	require.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		path := filepath.Join(t.TempDir(), "allocations.csv")
+		require.NoError(t, ioutil.WriteFile(path, b, 0o644))
+
+		// Malformed tables are rejected with an error, never a panic.
+		_, _ = stakinggenesis.LoadEntityAllocationTable(path, config)
+	})
+}
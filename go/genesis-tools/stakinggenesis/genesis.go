@@ -0,0 +1,49 @@
+package stakinggenesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/genesischeck"
+	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// LoadStakingGenesis loads a staking genesis, either on its own, as written
+// by staking_genesis, or as the staking section of a full genesis document.
+// The document is only returned for a full genesis document. A staking
+// genesis without a token symbol or a ledger is rejected, so that an empty
+// file is not mistaken for one.
+func LoadStakingGenesis(path string) (*staking.Genesis, *genesis.Document, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sections struct {
+		Staking json.RawMessage `json:"staking"`
+	}
+	if err = json.Unmarshal(b, &sections); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if sections.Staking == nil {
+		var stakingGenesis staking.Genesis
+		if err = json.Unmarshal(b, &stakingGenesis); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		switch {
+		case stakingGenesis.TokenSymbol == "":
+			return nil, nil, fmt.Errorf("%s: staking genesis has no token symbol", path)
+		case len(stakingGenesis.Ledger) == 0:
+			return nil, nil, fmt.Errorf("%s: staking genesis has no ledger", path)
+		}
+		return &stakingGenesis, nil, nil
+	}
+
+	doc, err := genesischeck.LoadDocument(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &doc.Staking, doc, nil
+}
@@ -0,0 +1,57 @@
+package stakinggenesis_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
+)
+
+const stakingGenesisPath = "testdata/staking_genesis.json"
+
+func TestLoadStakingGenesis(t *testing.T) {
+	stakingGenesis, doc, err := stakinggenesis.LoadStakingGenesis(stakingGenesisPath)
+	require.NoError(t, err)
+	require.Nil(t, doc, "a staking genesis is not a full document")
+	require.NotEmpty(t, stakingGenesis.Ledger)
+
+	dir, err := ioutil.TempDir("", "stakinggenesis")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The staking section of a full genesis document.
+	b, err := json.Marshal(&genesis.Document{ChainID: "test", Staking: *stakingGenesis})
+	require.NoError(t, err)
+	docPath := filepath.Join(dir, "genesis.json")
+	require.NoError(t, ioutil.WriteFile(docPath, b, 0o644))
+
+	fromDoc, doc, err := stakinggenesis.LoadStakingGenesis(docPath)
+	require.NoError(t, err)
+	require.NotNil(t, doc)
+	require.Equal(t, "test", doc.ChainID)
+	require.Equal(t, stakingGenesis, fromDoc)
+
+	malformedPath := filepath.Join(dir, "malformed.json")
+	require.NoError(t, ioutil.WriteFile(malformedPath, []byte(`{"ledger": [`), 0o644))
+	_, _, err = stakinggenesis.LoadStakingGenesis(malformedPath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), malformedPath)
+
+	for _, tc := range []struct {
+		content, err string
+	}{
+		{`{}`, "staking genesis has no token symbol"},
+		{`{"token_symbol": "ROSE"}`, "staking genesis has no ledger"},
+	} {
+		emptyPath := filepath.Join(dir, "empty.json")
+		require.NoError(t, ioutil.WriteFile(emptyPath, []byte(tc.content), 0o644))
+		_, _, err = stakinggenesis.LoadStakingGenesis(emptyPath)
+		require.EqualError(t, err, emptyPath+": "+tc.err)
+	}
+}
@@ -0,0 +1,104 @@
+package stakinggenesis_test
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenEntities are fixture entities whose keys are derived from their
+// names, so that the generated ledgers can be pinned.
+type goldenEntities map[string]*entity.Entity
+
+func newGoldenEntities(t *testing.T, names ...string) goldenEntities {
+	entities := make(goldenEntities)
+	for _, name := range names {
+		seed := sha512.Sum512([]byte("mainnet-entities/golden/" + name))
+		signer, err := memorySigner.NewSigner(bytes.NewReader(seed[:]))
+		require.NoError(t, err)
+		entities[name] = &entity.Entity{
+			Versioned: cbor.NewVersioned(entity.LatestEntityDescriptorVersion),
+			ID:        signer.Public(),
+		}
+	}
+	return entities
+}
+
+func (e goldenEntities) All() map[string]*entity.Entity {
+	return e
+}
+
+func (e goldenEntities) ResolveEntity(name string) *entity.Entity {
+	return e[name]
+}
+
+// requireGolden compares the staking genesis with the golden file of the
+// name, or writes it when run with -update.
+func requireGolden(t *testing.T, name string, genesis interface{}) {
+	b, err := json.MarshalIndent(genesis, "", "  ")
+	require.NoError(t, err)
+	b = append(b, '\n')
+
+	path := filepath.Join("testdata", name+".json")
+	if *updateGolden {
+		require.NoError(t, ioutil.WriteFile(path, b, 0o644))
+		return
+	}
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "missing golden file, run the tests with -update to create it")
+	require.Equal(t, string(expected), string(b),
+		"the staking genesis differs from %s, run the tests with -update if the change is intended", path)
+}
+
+func TestGoldenStakingGenesis(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options func(options *stakinggenesis.GenesisOptions)
+	}{
+		{"staking_genesis", func(options *stakinggenesis.GenesisOptions) {}},
+		{"staking_genesis_test_only", func(options *stakinggenesis.GenesisOptions) {
+			options.Entities.(goldenEntities)["test5"] = newGoldenEntities(t, "test5")["test5"]
+			options.IsTestGenesis = true
+		}},
+		{"staking_genesis_adjustments", func(options *stakinggenesis.GenesisOptions) {
+			options.AdjustmentsPath = adjustmentsFixturePath
+		}},
+		{"staking_genesis_kyc", func(options *stakinggenesis.GenesisOptions) {
+			options.ConfigurationPath = writeConfig(t,
+				`  github_handle_label: "Github handle"`, kycCSVOptions,
+				"commission_rate: 5000", "commission_rate: 5000\nkyc_redirect_account: account1",
+			)
+			options.AllocationsPath = "fixtures/kyc_allocations.csv"
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			options := stakinggenesis.GenesisOptions{
+				Entities:                newGoldenEntities(t, "test1", "test2", "test3", "test4"),
+				ConsensusParametersPath: "fixtures/staking_params.json",
+				ConfigurationPath:       configFixturePath,
+				AllocationsPath:         "fixtures/allocations.csv",
+			}
+			tc.options(&options)
+
+			genesis, report, err := stakinggenesis.CreateWithReport(options)
+			require.NoError(t, err)
+			requireGolden(t, tc.name, genesis)
+			if len(report.Adjustments) > 0 || len(report.KYC) > 0 {
+				requireGolden(t, tc.name+"_report", report)
+			}
+		})
+	}
+}
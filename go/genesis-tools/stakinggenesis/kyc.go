@@ -0,0 +1,175 @@
+package stakinggenesis
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KYCStatus is the KYC status of an entity.
+type KYCStatus string
+
+const (
+	// KYCPending is an entity whose KYC is not complete.
+	KYCPending KYCStatus = "pending"
+	// KYCApproved is an entity that completed KYC.
+	KYCApproved KYCStatus = "approved"
+	// KYCRejected is an entity that failed KYC.
+	KYCRejected KYCStatus = "rejected"
+	// KYCExempt is an entity that does not need KYC, like one that only
+	// receives delegations.
+	KYCExempt KYCStatus = "exempt"
+)
+
+// KYCDateFormat is the format of KYC dates.
+const KYCDateFormat = "2006-01-02"
+
+// Allocations that KYC can block, used to identify a KYCBlock.
+const (
+	KYCBlockedFunding    = "funding"
+	KYCBlockedSelfStake  = "self_stake"
+	KYCBlockedDelegation = "delegation"
+)
+
+// kycStatuses are the known KYC statuses.
+var kycStatuses = map[KYCStatus]bool{
+	KYCPending:  true,
+	KYCApproved: true,
+	KYCRejected: true,
+	KYCExempt:   true,
+}
+
+// defaultKYCValues maps the values of the csv KYC column that need no
+// configuration, lowercased, to statuses. The statuses themselves are also
+// understood.
+var defaultKYCValues = map[string]KYCStatus{
+	"true":  KYCApproved,
+	"false": KYCPending,
+	"":      KYCPending,
+}
+
+// KYCRecord is the KYC status of an entity.
+type KYCRecord struct {
+	Status KYCStatus `yaml:"status" json:"status"`
+	Reason string    `yaml:"reason" json:"reason,omitempty"`
+	// Date is the date of the status as KYCDateFormat, it may be empty.
+	Date string `yaml:"date" json:"date,omitempty"`
+}
+
+// KYCPolicy is what an entity with a KYC status may receive.
+type KYCPolicy struct {
+	// Funding allows the funds of the allocation.
+	Funding bool `yaml:"funding"`
+	// SelfStake allows escrowing the funds above the minimum balance with
+	// the entity itself.
+	SelfStake bool `yaml:"self_stake"`
+	// Delegations allows the delegations of the allocation.
+	Delegations bool `yaml:"delegations"`
+}
+
+// KYCPolicies are the policies of the KYC statuses.
+type KYCPolicies map[KYCStatus]KYCPolicy
+
+// DefaultKYCPolicies returns the policies used for the mainnet launch. Only
+// approved and exempt entities are funded, pending entities still receive
+// delegations and rejected entities receive nothing.
+func DefaultKYCPolicies() KYCPolicies {
+	return KYCPolicies{
+		KYCApproved: {Funding: true, SelfStake: true, Delegations: true},
+		KYCExempt:   {Funding: true, SelfStake: true, Delegations: true},
+		KYCPending:  {Delegations: true},
+		KYCRejected: {},
+	}
+}
+
+// parseKYCStatus returns the status of a value of the csv KYC column. values
+// are configured values that take precedence over the defaults.
+func parseKYCStatus(value string, values map[string]KYCStatus) (KYCStatus, error) {
+	value = strings.TrimSpace(value)
+	if status, ok := values[value]; ok {
+		return status, nil
+	}
+	lower := strings.ToLower(value)
+	if status, ok := defaultKYCValues[lower]; ok {
+		return status, nil
+	}
+	if status := KYCStatus(lower); kycStatuses[status] {
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown KYC status %q", value)
+}
+
+// validate checks that the record has a known status and a well-formed date.
+func (r *KYCRecord) validate() error {
+	if !kycStatuses[r.Status] {
+		return fmt.Errorf("unknown KYC status %q", r.Status)
+	}
+	if r.Date == "" {
+		return nil
+	}
+	if _, err := time.Parse(KYCDateFormat, r.Date); err != nil {
+		return fmt.Errorf("malformed KYC date %q: %w", r.Date, err)
+	}
+	return nil
+}
+
+// KYCBlock is an allocation blocked by the KYC policy of an entity.
+type KYCBlock struct {
+	Entity string `json:"entity"`
+	KYCRecord
+	// Allocation is the kind of allocation that was blocked.
+	Allocation string `json:"allocation"`
+	// Account is the delegating account of a blocked delegation.
+	Account string `json:"account,omitempty"`
+	// Amount is in whole tokens.
+	Amount uint64 `json:"amount"`
+	// RedirectedTo is the account that was credited with the amount, empty
+	// if it was not redirected.
+	RedirectedTo string `json:"redirected_to,omitempty"`
+}
+
+// KYCReport are the allocations blocked by KYC.
+type KYCReport []*KYCBlock
+
+// sort orders the report by entity, then allocation and account.
+func (r KYCReport) sort() {
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Entity != r[j].Entity {
+			return r[i].Entity < r[j].Entity
+		}
+		if r[i].Allocation != r[j].Allocation {
+			return r[i].Allocation < r[j].Allocation
+		}
+		return r[i].Account < r[j].Account
+	})
+}
+
+// WriteCSV writes the report as csv.
+func (r KYCReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"entity", "status", "reason", "date", "allocation", "account", "amount", "redirected_to",
+	}); err != nil {
+		return err
+	}
+	for _, block := range r {
+		if err := writer.Write([]string{
+			block.Entity,
+			string(block.Status),
+			block.Reason,
+			block.Date,
+			block.Allocation,
+			block.Account,
+			strconv.FormatUint(block.Amount, 10),
+			block.RedirectedTo,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,185 @@
+package stakinggenesis_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+)
+
+const kycCSVOptions = `  github_handle_label: "Github handle"
+  kyc_reason_label: "KYC Reason"
+  kyc_date_label: "KYC Date"
+  kyc_values:
+    "Delegation only": exempt`
+
+func kycGenesisOptions(t *testing.T, replacements ...string) stakinggenesis.GenesisOptions {
+	options := genericGenesisOptions([]string{
+		"test1",
+		"test2",
+		"test3",
+		"test4",
+	})
+	replacements = append([]string{`  github_handle_label: "Github handle"`, kycCSVOptions}, replacements...)
+	options.ConfigurationPath = writeConfig(t, replacements...)
+	options.AllocationsPath = "fixtures/kyc_allocations.csv"
+	return options
+}
+
+func TestGenerateStakingLedgerKYC(t *testing.T) {
+	options := kycGenesisOptions(t)
+	genesis, report, err := stakinggenesis.CreateWithReport(options)
+	require.NoError(t, err)
+
+	validator := newValidator(genesis, options.Entities)
+	validator.requireCorrectTotals(t,
+		6_800_000_000_000_000_000,
+		10_000_000_000_000_000_000,
+	)
+	// Approved.
+	validator.requireGeneralBalance(t, "test1", 100_000_000_000)
+	validator.requireEscrowBalance(t, "test1", 199_999_900_000_000_000)
+	// Pending, delegations only.
+	validator.requireGeneralBalance(t, "test2", 0)
+	validator.requireEscrowBalance(t, "test2", 100_000_000_000_000_000)
+	// Rejected, nothing.
+	validator.requireGeneralBalance(t, "test3", 0)
+	validator.requireEscrowBalance(t, "test3", 0)
+	validator.requireGeneralBalance(t, "account2", 1_000_000_000_000_000_000)
+	// Exempt, with a blank funding column.
+	validator.requireEscrowBalance(t, "test4", 1_000_000_000_000)
+
+	require.Equal(t, stakinggenesis.KYCReport{
+		{
+			Entity: "test2",
+			KYCRecord: stakinggenesis.KYCRecord{
+				Status: stakinggenesis.KYCPending,
+				Reason: "documents requested",
+				Date:   "2020-09-10",
+			},
+			Allocation: stakinggenesis.KYCBlockedFunding,
+			Amount:     100_000_000,
+		},
+		{
+			Entity: "test3",
+			KYCRecord: stakinggenesis.KYCRecord{
+				Status: stakinggenesis.KYCRejected,
+				Reason: "sanctions screening",
+				Date:   "2020-09-12",
+			},
+			Allocation: stakinggenesis.KYCBlockedDelegation,
+			Account:    "account2",
+			Amount:     100_000_000,
+		},
+		{
+			Entity: "test3",
+			KYCRecord: stakinggenesis.KYCRecord{
+				Status: stakinggenesis.KYCRejected,
+				Reason: "sanctions screening",
+				Date:   "2020-09-12",
+			},
+			Allocation: stakinggenesis.KYCBlockedFunding,
+			Amount:     1_000,
+		},
+	}, report.KYC)
+
+	var b bytes.Buffer
+	require.NoError(t, report.KYC.WriteCSV(&b))
+	require.Equal(t, `entity,status,reason,date,allocation,account,amount,redirected_to
+test2,pending,documents requested,2020-09-10,funding,,100000000,
+test3,rejected,sanctions screening,2020-09-12,delegation,account2,100000000,
+test3,rejected,sanctions screening,2020-09-12,funding,,1000,
+`, b.String())
+}
+
+func TestGenerateStakingLedgerKYCRedirect(t *testing.T) {
+	options := kycGenesisOptions(t, "commission_rate: 5000", "commission_rate: 5000\nkyc_redirect_account: account1")
+	genesis, report, err := stakinggenesis.CreateWithReport(options)
+	require.NoError(t, err)
+
+	// Blocked funding is taken from the common pool and the delegation of
+	// account2 is moved to account1.
+	validator := newValidator(genesis, options.Entities)
+	validator.requireCorrectTotals(t,
+		6_699_999_000_000_000_000,
+		10_000_000_000_000_000_000,
+	)
+	validator.requireGeneralBalance(t, "account1", 2_100_000_000_000_000_000)
+	validator.requireGeneralBalance(t, "account2", 900_000_000_000_000_000)
+	for _, block := range report.KYC {
+		require.Equal(t, "account1", block.RedirectedTo)
+	}
+}
+
+func TestGenerateStakingLedgerKYCPolicies(t *testing.T) {
+	options := kycGenesisOptions(t, "commission_rate: 5000", `commission_rate: 5000
+kyc_policies:
+  pending:
+    funding: true
+    delegations: true`)
+	genesis, report, err := stakinggenesis.CreateWithReport(options)
+	require.NoError(t, err)
+
+	// Pending entities are funded but do not stake to themselves.
+	validator := newValidator(genesis, options.Entities)
+	validator.requireGeneralBalance(t, "test2", 100_000_000_000_000_000)
+	validator.requireEscrowBalance(t, "test2", 100_000_000_000_000_000)
+	require.Equal(t, "test2", report.KYC[0].Entity)
+	require.Equal(t, stakinggenesis.KYCBlockedSelfStake, report.KYC[0].Allocation)
+	require.Equal(t, uint64(99_999_900), report.KYC[0].Amount)
+}
+
+func TestLoadGenesisConfigKYC(t *testing.T) {
+	path := writeConfig(t,
+		`  github_handle_label: "Github handle"`, kycCSVOptions+"\n    Maybe: unsure\n    Perhaps: unclear",
+		"commission_rate: 5000", `commission_rate: 5000
+kyc_redirect_account: account3
+kyc_policies:
+  denied: {}
+  refused: {}`,
+	)
+	// The problems are in document order, even for fields checked in map
+	// order.
+	for i := 0; i < 10; i++ {
+		_, err := stakinggenesis.LoadGenesisConfig(path)
+		requireConfigErrors(t, err,
+			`line 41: csv_options.kyc_values.maybe: unknown KYC status "unsure"`,
+			`line 42: csv_options.kyc_values.perhaps: unknown KYC status "unclear"`,
+			`line 61: kyc_redirect_account: unknown account "account3"`,
+			"line 63: kyc_policies.denied: unknown KYC status",
+			"line 64: kyc_policies.refused: unknown KYC status",
+		)
+	}
+}
+
+func TestLoadAllocationsKYC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allocations")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b, err := ioutil.ReadFile("fixtures/kyc_allocations.csv")
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		old, new string
+		err      string
+	}{
+		{"TRUE,pending,", "TRUE,maybe,", `row 3: unknown KYC status "maybe"`},
+		{"2020-09-10", "10/09/2020", `row 3: malformed KYC date "10/09/2020"`},
+		{`"1,000",0,"100`, `"1.000",0,"100`, `row 4: strconv.ParseUint: parsing "1.000": invalid syntax`},
+		{`,,,"1,000",0`, `,,,"1,000",x`, `row 5: strconv.ParseUint: parsing "x": invalid syntax`},
+	} {
+		path := filepath.Join(dir, "allocations.csv")
+		require.NoError(t, ioutil.WriteFile(path, bytes.Replace(b, []byte(tc.old), []byte(tc.new), 1), 0o644))
+
+		options := kycGenesisOptions(t)
+		options.AllocationsPath = path
+		_, err = stakinggenesis.Create(options)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), tc.err)
+	}
+}
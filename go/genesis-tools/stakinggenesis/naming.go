@@ -0,0 +1,144 @@
+package stakinggenesis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EntityPackageSuffix is the suffix every submitted entity package archive
+// must have. The package name is the file name without this suffix.
+const EntityPackageSuffix = "-entity.tar.gz"
+
+var defaultPackageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// PackageNamePolicy is the naming policy enforced on entity packages.
+type PackageNamePolicy struct {
+	// Pattern is the pattern that every package name must match.
+	Pattern *regexp.Regexp
+}
+
+// DefaultPackageNamePolicy returns the naming policy used for mainnet
+// submissions. Names are GitHub handles, optionally with underscores.
+func DefaultPackageNamePolicy() PackageNamePolicy {
+	return PackageNamePolicy{
+		Pattern: defaultPackageNamePattern,
+	}
+}
+
+// ValidateName checks that a package name is allowed by the policy.
+func (p PackageNamePolicy) ValidateName(name string) error {
+	pattern := p.Pattern
+	if pattern == nil {
+		pattern = defaultPackageNamePattern
+	}
+	if !pattern.MatchString(name) {
+		return fmt.Errorf(`entity package name "%s" does not match %s`, name, pattern)
+	}
+	return nil
+}
+
+// PackageNameFromFileName returns the package name of an entity package
+// archive file name. The file name must end in EntityPackageSuffix.
+func (p PackageNamePolicy) PackageNameFromFileName(fileName string) (string, error) {
+	if !strings.HasSuffix(fileName, EntityPackageSuffix) {
+		return "", fmt.Errorf(`entity package "%s" must be named <name>%s`, fileName, EntityPackageSuffix)
+	}
+	name := strings.TrimSuffix(fileName, EntityPackageSuffix)
+	if err := p.ValidateName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// packageNameSet tracks package names by their case-folded form so that
+// names differing only in case are detected.
+type packageNameSet map[string][]string
+
+func (s packageNameSet) add(name, location string) {
+	folded := strings.ToLower(name)
+	s[folded] = append(s[folded], location)
+}
+
+// conflicts returns the locations of every case-folded name that was added
+// more than once.
+func (s packageNameSet) conflicts() map[string][]string {
+	conflicts := make(map[string][]string)
+	for folded, locations := range s {
+		if len(locations) < 2 {
+			continue
+		}
+		sorted := append([]string{}, locations...)
+		sort.Strings(sorted)
+		conflicts[folded] = sorted
+	}
+	return conflicts
+}
+
+// duplicates returns an error listing every case-folded name that was added
+// more than once, or nil.
+func (s packageNameSet) duplicates() error {
+	var conflicts []string
+	for folded, locations := range s.conflicts() {
+		conflicts = append(conflicts, fmt.Sprintf(`"%s" (%s)`, folded, strings.Join(locations, ", ")))
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+	return fmt.Errorf("duplicate entity package names: %s", strings.Join(conflicts, "; "))
+}
+
+// packageErrors returns an error for every package directory whose name
+// collides with another package. Locations must be package paths.
+func (s packageNameSet) packageErrors() LoadErrors {
+	var folded []string
+	conflicts := s.conflicts()
+	for name := range conflicts {
+		folded = append(folded, name)
+	}
+	sort.Strings(folded)
+
+	var errs LoadErrors
+	for _, name := range folded {
+		for _, location := range conflicts[name] {
+			errs = append(errs, &PackageError{
+				Name:     name,
+				Path:     location,
+				Category: CategoryDuplicate,
+				Err:      fmt.Errorf(`duplicate entity package names: "%s" (%s)`, name, strings.Join(conflicts[name], ", ")),
+			})
+		}
+	}
+	return errs
+}
+
+// ValidatePackageFileNames checks a list of entity package archive file names
+// against the policy and ensures that no two names collide when case-folded.
+func (p PackageNamePolicy) ValidatePackageFileNames(fileNames []string) error {
+	names := make(packageNameSet)
+	for _, fileName := range fileNames {
+		if _, err := p.PackageNameFromFileName(fileName); err != nil {
+			return err
+		}
+		names.add(strings.TrimSuffix(fileName, EntityPackageSuffix), fileName)
+	}
+	return names.duplicates()
+}
+
+// CheckGithubHandles ensures that every entity package name appears in the
+// given set of GitHub handles. Handles are compared case-insensitively.
+func CheckGithubHandles(entities Entities, handles map[string]bool) error {
+	var missing []string
+	for name := range entities.All() {
+		if !handles[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("entity packages without a matching github handle: %s", strings.Join(missing, ", "))
+}
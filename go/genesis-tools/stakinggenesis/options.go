@@ -10,11 +10,24 @@ import (
 // GenesisOptions options for the staking genesis document.
 type GenesisOptions struct {
 	IsTestGenesis             bool
+	RequireGithubHandles      bool
 	ConfigurationPath         string
 	AllocationsPath           string
 	ConsensusParametersPath   string
 	ConsensusParametersLoader func() staking.ConsensusParameters
 	Entities                  Entities
+	// ExcludedEntities are the names of entity packages that were excluded
+	// from Entities. Their allocations are skipped.
+	ExcludedEntities []string
+	// BaseGenesis seeds the ledger with an existing staking genesis, like
+	// one of a state dump. Accounts, allocations and delegations are added on
+	// top of it. Configured accounts that are already in it are kept as they
+	// are, the allocations of entities that are already in it are added to
+	// their accounts and delegations.
+	BaseGenesis *staking.Genesis
+	// AdjustmentsPath is a yaml file of ledger adjustments applied after the
+	// allocations, see LoadAdjustments.
+	AdjustmentsPath string
 }
 
 func (g GenesisOptions) LoadConsensusParameters() (*staking.ConsensusParameters, error) {
@@ -0,0 +1,205 @@
+package stakinggenesis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// Node policy rules, used to identify a PolicyViolation.
+const (
+	RuleNodeRequired        = "node_required"
+	RuleNodeRegistered      = "node_registered"
+	RuleAllowedRoles        = "allowed_roles"
+	RuleMaxNodeExpiration   = "max_node_expiration"
+	RuleConsensusAddress    = "consensus_address"
+	RuleNoRuntimes          = "no_runtimes"
+	RuleNoEntitySignedNodes = "no_entity_signed_nodes"
+)
+
+// roleNames maps the role names used by node.RolesMask.String to roles.
+var roleNames = map[string]node.RolesMask{
+	"compute":       node.RoleComputeWorker,
+	"storage":       node.RoleStorageWorker,
+	"key-manager":   node.RoleKeyManager,
+	"validator":     node.RoleValidator,
+	"consensus-rpc": node.RoleConsensusRPC,
+}
+
+// NodePolicy is the set of rules every entity package's node descriptor must
+// follow to be included at genesis.
+type NodePolicy struct {
+	// RequireNode requires every package to contain a node descriptor.
+	RequireNode bool `yaml:"require_node"`
+	// AllowedRoles are the node roles that may be registered at genesis.
+	AllowedRoles []string `yaml:"allowed_roles"`
+	// MaxNodeExpiration is the registry's max_node_expiration. Node
+	// expirations must not exceed it. Unset disables the check, it is
+	// usually left unset and taken from the registry parameters with
+	// WithRegistryParameters.
+	MaxNodeExpiration *uint64 `yaml:"max_node_expiration"`
+	// RequireConsensusAddress requires at least one well-formed consensus
+	// address.
+	RequireConsensusAddress bool `yaml:"require_consensus_address"`
+	// AllowUnroutableAddresses allows consensus addresses that are not
+	// globally routable, for test networks.
+	AllowUnroutableAddresses bool `yaml:"allow_unroutable_addresses"`
+	// AllowRuntimes allows nodes to register runtimes.
+	AllowRuntimes bool `yaml:"allow_runtimes"`
+	// AllowEntitySignedNodes allows entities to enable entity signed nodes.
+	AllowEntitySignedNodes bool `yaml:"allow_entity_signed_nodes"`
+}
+
+// DefaultNodePolicy returns the node policy used for the mainnet launch. Its
+// max node expiration is set by WithRegistryParameters.
+func DefaultNodePolicy() *NodePolicy {
+	return &NodePolicy{
+		RequireNode:             true,
+		AllowedRoles:            []string{"validator"},
+		RequireConsensusAddress: true,
+	}
+}
+
+// WithRegistryParameters returns a copy of the policy that checks node
+// expirations against the registry's max_node_expiration. It fails if the
+// policy sets a different max node expiration, so that the two cannot drift
+// apart.
+func (p *NodePolicy) WithRegistryParameters(params registry.ConsensusParameters) (*NodePolicy, error) {
+	if p.MaxNodeExpiration != nil && *p.MaxNodeExpiration != params.MaxNodeExpiration {
+		return nil, fmt.Errorf("node policy max_node_expiration %d differs from the registry max_node_expiration %d",
+			*p.MaxNodeExpiration, params.MaxNodeExpiration)
+	}
+	policy := *p
+	maxNodeExpiration := params.MaxNodeExpiration
+	policy.MaxNodeExpiration = &maxNodeExpiration
+	return &policy, nil
+}
+
+// LoadNodePolicy loads a node policy from a yaml file.
+func LoadNodePolicy(path string) (*NodePolicy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy NodePolicy
+	if err = yaml.UnmarshalStrict(b, &policy); err != nil {
+		return nil, err
+	}
+	if _, err = policy.allowedRoles(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (p *NodePolicy) allowedRoles() (node.RolesMask, error) {
+	var mask node.RolesMask
+	for _, name := range p.AllowedRoles {
+		role, ok := roleNames[name]
+		if !ok {
+			return 0, fmt.Errorf(`unknown node role "%s"`, name)
+		}
+		mask |= role
+	}
+	return mask, nil
+}
+
+// PolicyViolation is a rule of a NodePolicy broken by an entity package.
+type PolicyViolation struct {
+	Package string
+	Rule    string
+	Message string
+}
+
+func (v PolicyViolation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Package, v.Message, v.Rule)
+}
+
+// Check returns every rule of the policy the entity package violates.
+func (p *NodePolicy) Check(pkg *EntityPackage) []PolicyViolation {
+	var violations []PolicyViolation
+	violate := func(rule, format string, args ...interface{}) {
+		violations = append(violations, PolicyViolation{
+			Package: pkg.Name,
+			Rule:    rule,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if pkg.Entity.AllowEntitySignedNodes && !p.AllowEntitySignedNodes {
+		violate(RuleNoEntitySignedNodes, "entity allows entity signed nodes")
+	}
+
+	n := pkg.Node
+	if n == nil {
+		if p.RequireNode {
+			violate(RuleNodeRequired, "package has no node descriptor")
+		}
+		return violations
+	}
+
+	if !n.EntityID.Equal(pkg.Entity.ID) {
+		violate(RuleNodeRegistered, "node belongs to entity %s", n.EntityID)
+	}
+	var registered bool
+	for _, id := range pkg.Entity.Nodes {
+		if id.Equal(n.ID) {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		violate(RuleNodeRegistered, "node %s is not listed in the entity descriptor", n.ID)
+	}
+
+	allowedRoles, err := p.allowedRoles()
+	if err != nil {
+		violate(RuleAllowedRoles, "%s", err)
+	} else if n.Roles == 0 || n.Roles&^allowedRoles != 0 {
+		violate(RuleAllowedRoles, `node roles "%s" not in allowed roles "%s"`, n.Roles, strings.Join(p.AllowedRoles, ","))
+	}
+
+	if p.MaxNodeExpiration != nil && n.Expiration > *p.MaxNodeExpiration {
+		violate(RuleMaxNodeExpiration, "node expiration %d exceeds max_node_expiration %d", n.Expiration, *p.MaxNodeExpiration)
+	}
+
+	if p.RequireConsensusAddress && len(n.Consensus.Addresses) == 0 {
+		violate(RuleConsensusAddress, "node has no consensus address")
+	}
+	for _, address := range n.Consensus.Addresses {
+		address := address
+		switch {
+		case !address.ID.IsValid():
+			violate(RuleConsensusAddress, "consensus address %s has an invalid ID", address.String())
+		case address.Address.IP == nil || address.Address.Port <= 0 || address.Address.Port > 65535:
+			violate(RuleConsensusAddress, "consensus address %s is malformed", address.String())
+		case !p.AllowUnroutableAddresses && !address.Address.IsRoutable():
+			violate(RuleConsensusAddress, "consensus address %s is not routable", address.String())
+		}
+	}
+
+	if len(n.Runtimes) > 0 && !p.AllowRuntimes {
+		violate(RuleNoRuntimes, "node registers %d runtime(s)", len(n.Runtimes))
+	}
+
+	return violations
+}
+
+// CheckAll returns every policy violation of the given packages, ordered by
+// package name.
+func (p *NodePolicy) CheckAll(packages []*EntityPackage) []PolicyViolation {
+	var violations []PolicyViolation
+	for _, pkg := range packages {
+		violations = append(violations, p.Check(pkg)...)
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Package < violations[j].Package
+	})
+	return violations
+}
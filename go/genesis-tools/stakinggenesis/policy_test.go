@@ -0,0 +1,123 @@
+package stakinggenesis_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func loadTestPackages(t *testing.T, packages ...testPackage) []*stakinggenesis.EntityPackage {
+	dir := tempDir(t)
+	for _, pkg := range packages {
+		pkg.write(t, dir)
+	}
+	entities, err := stakinggenesis.LoadEntitiesDirectory([]string{dir})
+	require.NoError(t, err)
+	return entities.Packages()
+}
+
+func violatedRules(violations []stakinggenesis.PolicyViolation) map[string][]string {
+	rules := make(map[string][]string)
+	for _, violation := range violations {
+		rules[violation.Package] = append(rules[violation.Package], violation.Rule)
+	}
+	return rules
+}
+
+func TestNodePolicy(t *testing.T) {
+	packages := loadTestPackages(t,
+		testPackage{name: "valid", seed: "valid"},
+		testPackage{name: "no-node", seed: "no-node", withoutNode: true},
+		testPackage{name: "compute", seed: "compute", nodeFn: func(n *node.Node) {
+			n.AddRoles(node.RoleComputeWorker)
+		}},
+		testPackage{name: "expiration", seed: "expiration", nodeFn: func(n *node.Node) {
+			n.Expiration = 3
+		}},
+		testPackage{name: "no-address", seed: "no-address", nodeFn: func(n *node.Node) {
+			n.Consensus.Addresses = nil
+		}},
+		testPackage{name: "private-address", seed: "private-address", nodeFn: func(n *node.Node) {
+			var address node.ConsensusAddress
+			require.NoError(t, address.UnmarshalText([]byte(n.P2P.ID.String()+"@127.0.0.1:26656")))
+			n.Consensus.Addresses = []node.ConsensusAddress{address}
+		}},
+		testPackage{name: "runtimes", seed: "runtimes", nodeFn: func(n *node.Node) {
+			n.Runtimes = []*node.Runtime{{}}
+		}},
+		testPackage{name: "entity-signed", seed: "entity-signed", entityFn: func(e *entity.Entity) {
+			e.AllowEntitySignedNodes = true
+		}},
+		testPackage{name: "unregistered", seed: "unregistered", entityFn: func(e *entity.Entity) {
+			e.Nodes = nil
+		}},
+	)
+
+	policy, err := stakinggenesis.DefaultNodePolicy().WithRegistryParameters(registry.ConsensusParameters{MaxNodeExpiration: 2})
+	require.NoError(t, err)
+	violations := policy.CheckAll(packages)
+	require.Equal(t, map[string][]string{
+		"no-node":         {stakinggenesis.RuleNodeRequired},
+		"compute":         {stakinggenesis.RuleAllowedRoles},
+		"expiration":      {stakinggenesis.RuleMaxNodeExpiration},
+		"no-address":      {stakinggenesis.RuleConsensusAddress},
+		"private-address": {stakinggenesis.RuleConsensusAddress},
+		"runtimes":        {stakinggenesis.RuleNoRuntimes},
+		"entity-signed":   {stakinggenesis.RuleNoEntitySignedNodes},
+		"unregistered":    {stakinggenesis.RuleNodeRegistered},
+	}, violatedRules(violations))
+}
+
+func TestNodePolicyWithRegistryParameters(t *testing.T) {
+	policy := stakinggenesis.DefaultNodePolicy()
+	withParams, err := policy.WithRegistryParameters(registry.ConsensusParameters{MaxNodeExpiration: 5})
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), *withParams.MaxNodeExpiration)
+	require.Nil(t, policy.MaxNodeExpiration)
+
+	// A policy that sets its own max node expiration must agree with the
+	// registry.
+	withParams, err = withParams.WithRegistryParameters(registry.ConsensusParameters{MaxNodeExpiration: 5})
+	require.NoError(t, err)
+	_, err = withParams.WithRegistryParameters(registry.ConsensusParameters{MaxNodeExpiration: 2})
+	require.EqualError(t, err, "node policy max_node_expiration 5 differs from the registry max_node_expiration 2")
+}
+
+func TestLoadNodePolicy(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "policy.yaml")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+allowed_roles: [validator, compute]
+max_node_expiration: 5
+allow_unroutable_addresses: true
+`), 0o644))
+	policy, err := stakinggenesis.LoadNodePolicy(path)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), *policy.MaxNodeExpiration)
+	require.False(t, policy.RequireNode)
+
+	packages := loadTestPackages(t,
+		testPackage{name: "compute", seed: "compute", nodeFn: func(n *node.Node) {
+			n.AddRoles(node.RoleComputeWorker)
+			n.Expiration = 5
+		}},
+		testPackage{name: "no-node", seed: "no-node", withoutNode: true},
+	)
+	require.Empty(t, policy.CheckAll(packages))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("allowed_roles: [miner]\n"), 0o644))
+	_, err = stakinggenesis.LoadNodePolicy(path)
+	require.Error(t, err)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("alowed_roles: [validator]\n"), 0o644))
+	_, err = stakinggenesis.LoadNodePolicy(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package stakinggenesis_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// accountingOpSize is the number of bytes that encode an operation of
+// runAccountingOps.
+const accountingOpSize = 5
+
+// propertyAddresses are the few accounts the operations of runAccountingOps
+// pick from, so that they collide often.
+var propertyAddresses = func() []staking.Address {
+	var addresses []staking.Address
+	for i := 0; i < 6; i++ {
+		pub := signature.NewPublicKey(fmt.Sprintf("%064x", i+1))
+		addresses = append(addresses, staking.NewAddress(pub))
+	}
+	return addresses
+}()
+
+// propertyGenesis returns an empty accounting genesis in base units, or one
+// seeded with rewarded and debonding pools.
+func propertyGenesis(t *testing.T, seeded bool) *stakinggenesis.AccountingGenesis {
+	if !seeded {
+		return stakinggenesis.NewAccountingGenesis(quantity.NewFromUint64(1), 1<<40, 10000, 0, 5000)
+	}
+
+	base := dumpedGenesis(propertyAddresses[0], propertyAddresses[1])
+	base.TokenValueExponent = 0
+	require.NoError(t, base.TotalSupply.Add(quantity.NewFromUint64(1<<40)))
+	require.NoError(t, base.CommonPool.Add(quantity.NewFromUint64(1<<40)))
+	genesis, err := stakinggenesis.NewAccountingGenesisFromGenesis(base, 10000, 0, 5000)
+	require.NoError(t, err)
+	return genesis
+}
+
+// runAccountingOps applies the operations encoded by b to the genesis and
+// checks the invariants after every one of them, whether it failed or not.
+// Every operation is an opcode, two account indices and a 16 bit amount.
+func runAccountingOps(t *testing.T, genesis *stakinggenesis.AccountingGenesis, b []byte) {
+	for ; len(b) >= accountingOpSize; b = b[accountingOpSize:] {
+		from := propertyAddresses[int(b[1])%len(propertyAddresses)]
+		to := propertyAddresses[int(b[2])%len(propertyAddresses)]
+		amount := quantity.NewFromUint64(uint64(binary.BigEndian.Uint16(b[3:])))
+
+		var err error
+		switch b[0] % 7 {
+		case 0, 1:
+			err = genesis.AddAccount(from, amount)
+		case 2, 3:
+			err = genesis.AddDelegation(from, to, amount)
+		case 4:
+			if amount.IsZero() {
+				amount = nil
+			}
+			err = genesis.RemoveDelegation(from, to, amount)
+		case 5:
+			err = genesis.TransferBalance(from, to, amount)
+		case 6:
+			err = genesis.RemoveAccount(from)
+		}
+		// Operations are expected to fail, like delegating more than the
+		// balance, but never to leave the ledger inconsistent.
+		_ = err
+		requireAccountingInvariants(t, genesis)
+	}
+}
+
+// requireAccountingInvariants checks that the supply is conserved and that
+// the shares of every pool are accounted for.
+func requireAccountingInvariants(t *testing.T, genesis *stakinggenesis.AccountingGenesis) {
+	require.NoError(t, genesis.CheckInvariants())
+	partial, err := genesis.GetPartialGenesis()
+	require.NoError(t, err)
+
+	// The ledger, the common pool and the last block fees add up to the
+	// total supply.
+	total := partial.CommonPool.Clone()
+	require.NoError(t, total.Add(&partial.LastBlockFees))
+	for _, account := range partial.Ledger {
+		require.NoError(t, total.Add(&account.General.Balance))
+		require.NoError(t, total.Add(&account.Escrow.Active.Balance))
+		require.NoError(t, total.Add(&account.Escrow.Debonding.Balance))
+	}
+	require.Zero(t, total.Cmp(&partial.TotalSupply), "%s in the ledger for a total supply of %s", total, &partial.TotalSupply)
+
+	// The shares of the delegations add up to the shares of the pools, and
+	// a pool without shares has no balance left.
+	for address, account := range partial.Ledger {
+		var shares quantity.Quantity
+		for _, delegation := range partial.Delegations[address] {
+			require.False(t, delegation.Shares.IsZero(), "empty delegation to %s", address)
+			require.NoError(t, shares.Add(&delegation.Shares))
+		}
+		require.Zero(t, shares.Cmp(&account.Escrow.Active.TotalShares), "shares of the escrow of %s", address)
+		if shares.IsZero() {
+			require.True(t, account.Escrow.Active.Balance.IsZero(), "balance without shares in the escrow of %s", address)
+		}
+	}
+}
+
+func TestAccountingProperties(t *testing.T) {
+	for seed := int64(1); seed <= 200; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		ops := make([]byte, 100*accountingOpSize)
+		_, _ = rng.Read(ops)
+
+		seeded := seed%2 == 0
+		t.Run(fmt.Sprintf("seed=%d,seeded=%t", seed, seeded), func(t *testing.T) {
+			runAccountingOps(t, propertyGenesis(t, seeded), ops)
+		})
+	}
+}
+
+func TestParseHumanReadableNumberProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		n := rng.Uint64() >> uint(rng.Intn(64))
+
+		// Digits grouped by thousands, as spreadsheets write them.
+		digits := strconv.FormatUint(n, 10)
+		var grouped strings.Builder
+		for j, digit := range digits {
+			if j > 0 && (len(digits)-j)%3 == 0 {
+				grouped.WriteByte(',')
+			}
+			grouped.WriteRune(digit)
+		}
+
+		for _, s := range []string{digits, grouped.String()} {
+			parsed, err := stakinggenesis.ParseHumanReadableNumberToUint64(s)
+			require.NoError(t, err, s)
+			require.Equal(t, n, parsed, s)
+		}
+	}
+
+	for _, s := range []string{"", "-1", "1.5", "18,446,744,073,709,551,616", "10%"} {
+		_, err := stakinggenesis.ParseHumanReadableNumberToUint64(s)
+		require.Error(t, err, s)
+	}
+}
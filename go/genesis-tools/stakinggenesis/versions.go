@@ -0,0 +1,104 @@
+package stakinggenesis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+)
+
+// TargetOasisCoreRelease is the oasis-core release the network launches with.
+// Entity packages must be generated with this release's oasis-node CLI.
+const TargetOasisCoreRelease = "20.10"
+
+// DescriptorVersions are the entity and node descriptor versions accepted by
+// an oasis-core release.
+type DescriptorVersions struct {
+	// Release is the oasis-core release the versions were taken from.
+	Release string
+
+	MinEntity uint16
+	MaxEntity uint16
+	MinNode   uint16
+	MaxNode   uint16
+}
+
+// DefaultDescriptorVersions returns the descriptor versions accepted by
+// TargetOasisCoreRelease. The bounds are unexported by oasis-core, so they
+// are derived from what the ValidateBasic methods of the linked release
+// accept.
+func DefaultDescriptorVersions() *DescriptorVersions {
+	minEntity, maxEntity := acceptedVersions(entity.LatestEntityDescriptorVersion, func(v uint16) error {
+		ent := entity.Entity{Versioned: cbor.NewVersioned(v)}
+		return ent.ValidateBasic(false)
+	})
+	minNode, maxNode := acceptedVersions(node.LatestNodeDescriptorVersion, func(v uint16) error {
+		n := node.Node{Versioned: cbor.NewVersioned(v)}
+		return n.ValidateBasic(false)
+	})
+	return &DescriptorVersions{
+		Release:   TargetOasisCoreRelease,
+		MinEntity: minEntity,
+		MaxEntity: maxEntity,
+		MinNode:   minNode,
+		MaxNode:   maxNode,
+	}
+}
+
+// acceptedVersions returns the contiguous range of versions around latest
+// that validate accepts.
+func acceptedVersions(latest uint16, validate func(v uint16) error) (uint16, uint16) {
+	min, max := latest, latest
+	for min > 0 && validate(min-1) == nil {
+		min--
+	}
+	for max < math.MaxUint16 && validate(max+1) == nil {
+		max++
+	}
+	return min, max
+}
+
+// DescriptorVersionError is returned when a package contains a descriptor
+// with a version that the targeted release does not accept.
+type DescriptorVersionError struct {
+	Package    string
+	Descriptor string
+	Version    uint16
+	Min        uint16
+	Max        uint16
+	Release    string
+}
+
+func (e *DescriptorVersionError) Error() string {
+	return fmt.Sprintf(
+		`%s descriptor of "%s" has version %d but oasis-core %s accepts versions %d to %d, `+
+			"regenerate the entity package with the oasis-node CLI from oasis-core %s",
+		e.Descriptor, e.Package, e.Version, e.Release, e.Min, e.Max, e.Release,
+	)
+}
+
+func (d *DescriptorVersions) check(pkg, descriptor string, version, min, max uint16) error {
+	if version >= min && version <= max {
+		return nil
+	}
+	return &DescriptorVersionError{
+		Package:    pkg,
+		Descriptor: descriptor,
+		Version:    version,
+		Min:        min,
+		Max:        max,
+		Release:    d.Release,
+	}
+}
+
+// CheckEntity ensures the entity descriptor version is accepted.
+func (d *DescriptorVersions) CheckEntity(pkg string, ent *entity.Entity) error {
+	return d.check(pkg, "entity", ent.V, d.MinEntity, d.MaxEntity)
+}
+
+// CheckNode ensures the node descriptor version is accepted.
+func (d *DescriptorVersions) CheckNode(pkg string, n *node.Node) error {
+	return d.check(pkg, "node", n.V, d.MinNode, d.MaxNode)
+}
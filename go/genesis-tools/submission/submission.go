@@ -0,0 +1,266 @@
+// Package submission validates entity package submissions using a local git
+// checkout.
+package submission
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// EntitiesDir is the repository directory entity packages are submitted to.
+const EntitiesDir = "entities"
+
+// ChangedFile is a file changed between two git refs.
+type ChangedFile struct {
+	// Status is the git status letter of the change (A, M, D, ...).
+	Status string
+	Path   string
+}
+
+// Options options for validating a submission.
+type Options struct {
+	// RepositoryPath is the path of the git repository.
+	RepositoryPath string
+	// BaseRef is the ref the submission is merged into.
+	BaseRef string
+	// HeadRef is the ref of the submission.
+	HeadRef string
+	// Author is the GitHub handle of the submitter.
+	Author string
+	// NodePolicy is checked against the submitted package if set.
+	NodePolicy *stakinggenesis.NodePolicy
+	// RegistryParameters are the registry parameters of the network, the
+	// node policy takes its max node expiration from them. They are
+	// required with NodePolicy.
+	RegistryParameters *registry.ConsensusParameters
+}
+
+// Result is the outcome of validating a submission.
+type Result struct {
+	// PackagePath is the repository path of the submitted package.
+	PackagePath string
+	// Package is the loaded package, if it could be loaded.
+	Package *stakinggenesis.EntityPackage
+	// Problems are all of the problems found with the submission.
+	Problems []error
+}
+
+// Valid returns true if the submission has no problems.
+func (r *Result) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *Result) addProblem(err error) {
+	r.Problems = append(r.Problems, err)
+}
+
+// ExpectedPackagePath returns the repository path the author's entity package
+// must be submitted to.
+func ExpectedPackagePath(author string) string {
+	return EntitiesDir + "/" + author + stakinggenesis.EntityPackageSuffix
+}
+
+// ChangedFiles returns the files changed by headRef since it diverged from
+// baseRef, the same set of files a pull request shows.
+func ChangedFiles(repoPath, baseRef, headRef string) ([]ChangedFile, error) {
+	out, err := git(repoPath, "diff", "--name-status", "--no-renames", "-z", baseRef+"..."+headRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ChangedFile
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(fields) == 1 && fields[0] == "" {
+		return nil, nil
+	}
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("unexpected git diff output")
+	}
+	for i := 0; i < len(fields); i += 2 {
+		changes = append(changes, ChangedFile{
+			Status: fields[i],
+			Path:   fields[i+1],
+		})
+	}
+	return changes, nil
+}
+
+// CheckChangedFiles applies the submission rule: exactly one file may be
+// changed, the author's entity package, and it may not be deleted. The path
+// of the package is returned.
+func CheckChangedFiles(changes []ChangedFile, author string) (string, error) {
+	expected := ExpectedPackagePath(author)
+	switch {
+	case len(changes) == 0:
+		return "", fmt.Errorf("the submission does not change any files, expected %s", expected)
+	case len(changes) > 1:
+		return "", fmt.Errorf("the submission changes %d files, only %s may be changed without explicit review", len(changes), expected)
+	}
+
+	change := changes[0]
+	if change.Path != expected {
+		return "", fmt.Errorf("the entity file is expected to be named %s, found %s", expected, change.Path)
+	}
+	if change.Status != "A" && change.Status != "M" {
+		return "", fmt.Errorf("the entity file %s must be added or modified (git status %s)", expected, change.Status)
+	}
+	return change.Path, nil
+}
+
+// Validate validates a submission: the changed files are checked against the
+// submission rule, then the submitted package is unpacked and loaded like any
+// other entity package. Its keys must not be used by any of the packages
+// already in the entities directory at the base ref.
+func Validate(options Options) (*Result, error) {
+	nodePolicy := options.NodePolicy
+	if nodePolicy != nil {
+		if options.RegistryParameters == nil {
+			return nil, fmt.Errorf("the node policy requires the registry parameters")
+		}
+		var err error
+		if nodePolicy, err = nodePolicy.WithRegistryParameters(*options.RegistryParameters); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{}
+
+	policy := stakinggenesis.DefaultPackageNamePolicy()
+	if err := policy.ValidateName(options.Author); err != nil {
+		result.addProblem(err)
+		return result, nil
+	}
+
+	changes, err := ChangedFiles(options.RepositoryPath, options.BaseRef, options.HeadRef)
+	if err != nil {
+		return nil, err
+	}
+	packagePath, err := CheckChangedFiles(changes, options.Author)
+	if err != nil {
+		result.addProblem(err)
+		return result, nil
+	}
+	result.PackagePath = packagePath
+
+	archive, err := git(options.RepositoryPath, "show", options.HeadRef+":"+packagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	unpackDir, err := ioutil.TempDir("", "submission")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(unpackDir)
+
+	if err = stakinggenesis.UnpackEntityPackage(bytes.NewReader(archive), filepath.Join(unpackDir, options.Author)); err != nil {
+		result.addProblem(err)
+		return result, nil
+	}
+
+	entities, err := stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{unpackDir}, stakinggenesis.EntitiesDirectoryOptions{
+		NamePolicy: policy,
+		Versions:   stakinggenesis.DefaultDescriptorVersions(),
+	})
+	var loadErrs stakinggenesis.LoadErrors
+	switch {
+	case errors.As(err, &loadErrs):
+		for _, loadErr := range loadErrs {
+			result.addProblem(fmt.Errorf("%s [%s]: %w", packagePath, loadErr.Category, loadErr.Err))
+		}
+		return result, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if len(entities.Packages()) == 0 {
+		result.addProblem(fmt.Errorf("%s does not contain an entity package", packagePath))
+		return result, nil
+	}
+	result.Package = entities.Packages()[0]
+
+	existing, err := loadBasePackages(options, packagePath)
+	if err != nil {
+		return nil, err
+	}
+	var dupErr *stakinggenesis.DuplicateKeysError
+	if err = stakinggenesis.CheckDuplicateKeys(append(existing, result.Package)); errors.As(err, &dupErr) {
+		for _, conflict := range dupErr.Conflicts {
+			for _, name := range conflict.Packages {
+				if name == result.Package.Name {
+					result.addProblem(errors.New(conflict.String()))
+					break
+				}
+			}
+		}
+	}
+
+	if nodePolicy != nil {
+		for _, violation := range nodePolicy.Check(result.Package) {
+			result.addProblem(errors.New(violation.String()))
+		}
+	}
+	return result, nil
+}
+
+// loadBasePackages loads the entity packages in the entities directory at the
+// base ref, except for the submitted one, which may be replaced. The packages
+// were validated when they were merged, those that no longer load are left
+// out.
+func loadBasePackages(options Options, packagePath string) ([]*stakinggenesis.EntityPackage, error) {
+	out, err := git(options.RepositoryPath, "ls-tree", "-z", "--name-only", options.BaseRef, EntitiesDir+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	unpackDir, err := ioutil.TempDir("", "submission-base")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(unpackDir)
+
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path == packagePath || !strings.HasSuffix(path, stakinggenesis.EntityPackageSuffix) {
+			continue
+		}
+		archive, err := git(options.RepositoryPath, "show", options.BaseRef+":"+path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), stakinggenesis.EntityPackageSuffix)
+		if err = stakinggenesis.UnpackEntityPackage(bytes.NewReader(archive), filepath.Join(unpackDir, name)); err != nil {
+			continue
+		}
+	}
+
+	entities, err := stakinggenesis.LoadEntitiesDirectoryWithOptions([]string{unpackDir}, stakinggenesis.EntitiesDirectoryOptions{
+		NamePolicy:  stakinggenesis.DefaultPackageNamePolicy(),
+		SkipInvalid: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entities.Packages(), nil
+}
+
+func git(repoPath string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,224 @@
+package submission_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/stakinggenesis"
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/submission"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	dir, err := ioutil.TempDir("", "submission")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	repo := &testRepo{t: t, dir: dir}
+	repo.git("init", "-q")
+	repo.writeFile("README.md", []byte("entities\n"))
+	repo.commit("base")
+	repo.git("branch", "-M", "base")
+	return repo
+}
+
+func (r *testRepo) git(args ...string) {
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=test",
+		"-c", "user.email=test@example.com",
+		"-c", "commit.gpgsign=false",
+	}, args...)...)
+	cmd.Dir = r.dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(r.t, err, string(out))
+}
+
+func (r *testRepo) writeFile(name string, b []byte) {
+	path := filepath.Join(r.dir, filepath.FromSlash(name))
+	require.NoError(r.t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(r.t, ioutil.WriteFile(path, b, 0o644))
+}
+
+func (r *testRepo) commit(msg string) {
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", msg)
+}
+
+// entityArchive returns a gzipped tar entity package for an entity derived
+// from seed.
+func entityArchive(t *testing.T, seed string, version uint16) []byte {
+	return entityArchiveWithNote(t, seed, version, "ignored")
+}
+
+// entityArchiveWithNote returns an entity package like entityArchive with a
+// note in a file that is not part of the package, so that the archives of the
+// same entity can differ.
+func entityArchiveWithNote(t *testing.T, seed string, version uint16, note string) []byte {
+	signer := memorySigner.NewTestSigner(seed)
+	ent := &entity.Entity{
+		Versioned: cbor.NewVersioned(version),
+		ID:        signer.Public(),
+	}
+	signed, err := entity.SignEntity(signer, registry.RegisterGenesisEntitySignatureContext, ent)
+	require.NoError(t, err)
+	b, err := json.Marshal(signed)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string][]byte{
+		"entity/entity_genesis.json": b,
+		"entity/ignored.txt":         []byte(note),
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err = tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func (r *testRepo) validate(author string) *submission.Result {
+	result, err := submission.Validate(submission.Options{
+		RepositoryPath: r.dir,
+		BaseRef:        "base",
+		HeadRef:        "HEAD",
+		Author:         author,
+	})
+	require.NoError(r.t, err)
+	return result
+}
+
+func TestValidateSubmission(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.git("checkout", "-q", "-b", "submission")
+	repo.writeFile("entities/test1-entity.tar.gz", entityArchive(t, "test1", entity.LatestEntityDescriptorVersion))
+	repo.commit("add entity")
+
+	result := repo.validate("test1")
+	require.True(t, result.Valid(), "%v", result.Problems)
+	require.Equal(t, "entities/test1-entity.tar.gz", result.PackagePath)
+	require.Equal(t, memorySigner.NewTestSigner("test1").Public(), result.Package.Entity.ID)
+
+	// Only the author's package may be submitted.
+	result = repo.validate("test2")
+	require.False(t, result.Valid())
+	require.Contains(t, result.Problems[0].Error(), "expected to be named entities/test2-entity.tar.gz")
+
+	// No other files may be changed.
+	repo.writeFile("README.md", []byte("changed\n"))
+	repo.commit("change readme")
+	result = repo.validate("test1")
+	require.False(t, result.Valid())
+	require.Contains(t, result.Problems[0].Error(), "changes 2 files")
+}
+
+func TestValidateSubmissionInvalidPackage(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.git("checkout", "-q", "-b", "submission")
+	repo.writeFile("entities/test1-entity.tar.gz", entityArchive(t, "test1", 0))
+	repo.commit("add entity")
+
+	result := repo.validate("test1")
+	require.False(t, result.Valid())
+	require.Contains(t, result.Problems[0].Error(), "[version]")
+
+	repo.writeFile("entities/test1-entity.tar.gz", []byte("not an archive"))
+	repo.commit("break entity")
+	result = repo.validate("test1")
+	require.False(t, result.Valid())
+	require.Contains(t, result.Problems[0].Error(), "not gzipped")
+}
+
+func TestValidateSubmissionDuplicateKeys(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.git("checkout", "-q", "base")
+	repo.writeFile("entities/other-entity.tar.gz", entityArchive(t, "other", entity.LatestEntityDescriptorVersion))
+	repo.writeFile("entities/test1-entity.tar.gz", entityArchive(t, "test1", entity.LatestEntityDescriptorVersion))
+	repo.commit("add entities")
+	repo.git("checkout", "-q", "-b", "submission")
+
+	// A package may replace itself.
+	repo.writeFile("entities/test1-entity.tar.gz", entityArchiveWithNote(t, "test1", entity.LatestEntityDescriptorVersion, "updated"))
+	repo.commit("update entity")
+	result := repo.validate("test1")
+	require.True(t, result.Valid(), "%v", result.Problems)
+
+	// But not reuse the keys of another package.
+	repo.writeFile("entities/test1-entity.tar.gz", entityArchive(t, "other", entity.LatestEntityDescriptorVersion))
+	repo.commit("reuse entity")
+	result = repo.validate("test1")
+	require.False(t, result.Valid())
+	require.Len(t, result.Problems, 1)
+	require.Equal(t, "entity ID "+memorySigner.NewTestSigner("other").Public().String()+" is shared by packages other, test1",
+		result.Problems[0].Error())
+}
+
+func TestValidateSubmissionNodePolicy(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.git("checkout", "-q", "-b", "submission")
+	repo.writeFile("entities/test1-entity.tar.gz", entityArchive(t, "test1", entity.LatestEntityDescriptorVersion))
+	repo.commit("add entity")
+
+	options := submission.Options{
+		RepositoryPath: repo.dir,
+		BaseRef:        "base",
+		HeadRef:        "HEAD",
+		Author:         "test1",
+		NodePolicy:     stakinggenesis.DefaultNodePolicy(),
+	}
+	_, err := submission.Validate(options)
+	require.EqualError(t, err, "the node policy requires the registry parameters")
+
+	options.RegistryParameters = &registry.ConsensusParameters{MaxNodeExpiration: 2}
+	result, err := submission.Validate(options)
+	require.NoError(t, err)
+	require.False(t, result.Valid())
+	require.Contains(t, result.Problems[0].Error(), "package has no node descriptor")
+
+	// The node policy cannot disagree with the registry parameters.
+	maxNodeExpiration := uint64(5)
+	options.NodePolicy.MaxNodeExpiration = &maxNodeExpiration
+	_, err = submission.Validate(options)
+	require.EqualError(t, err, "node policy max_node_expiration 5 differs from the registry max_node_expiration 2")
+}
+
+func TestCheckChangedFiles(t *testing.T) {
+	_, err := submission.CheckChangedFiles([]submission.ChangedFile{
+		{Status: "D", Path: "entities/test1-entity.tar.gz"},
+	}, "test1")
+	require.Error(t, err)
+
+	_, err = submission.CheckChangedFiles(nil, "test1")
+	require.Error(t, err)
+
+	path, err := submission.CheckChangedFiles([]submission.ChangedFile{
+		{Status: "M", Path: "entities/test1-entity.tar.gz"},
+	}, "test1")
+	require.NoError(t, err)
+	require.Equal(t, "entities/test1-entity.tar.gz", path)
+}
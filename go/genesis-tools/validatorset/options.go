@@ -0,0 +1,35 @@
+package validatorset
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// Options options for previewing the initial validator set.
+type Options struct {
+	Staking    *staking.Genesis
+	Registry   *registry.Genesis
+	Parameters scheduler.ConsensusParameters
+}
+
+// LoadRegistryGenesis loads a registry genesis, as written by
+// registry_genesis.
+func LoadRegistryGenesis(path string) (*registry.Genesis, error) {
+	var registryGenesis registry.Genesis
+	if err := loadJSON(path, &registryGenesis); err != nil {
+		return nil, err
+	}
+	return &registryGenesis, nil
+}
+
+func loadJSON(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
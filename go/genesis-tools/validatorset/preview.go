@@ -0,0 +1,221 @@
+package validatorset
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// Reasons an entity misses the validator set.
+const (
+	ReasonNoValidatorNode    = "no registered validator node"
+	ReasonInsufficientStake  = "escrow does not cover the stake thresholds"
+	ReasonMaxValidators      = "below the max_validators cut"
+	ReasonUnregisteredEntity = "node entity is not registered"
+)
+
+// Candidate is an entity considered for the validator set.
+type Candidate struct {
+	EntityID signature.PublicKey
+	Address  staking.Address
+	// NodeID and ConsensusID identify the elected node, they are only set
+	// for validators.
+	NodeID      signature.PublicKey
+	ConsensusID signature.PublicKey
+	// Escrow is the active escrow balance of the entity.
+	Escrow      quantity.Quantity
+	VotingPower int64
+	// Reason is why the entity missed the cut, empty for validators.
+	Reason string
+}
+
+// Preview is the initial validator set as the scheduler would elect it.
+type Preview struct {
+	Validators []*Candidate
+	// Missed are the entities that miss the cut, ordered by escrow.
+	Missed []*Candidate
+	// TotalVotingPower is the sum of the validators' voting power.
+	TotalVotingPower int64
+	// TiedAtCut is true if the last validator has the same escrow as an
+	// entity that missed the max_validators cut. The scheduler breaks such
+	// ties with the beacon so the preview may pick a different entity.
+	TiedAtCut bool
+}
+
+// CheckMinValidators returns an error if fewer validators than
+// min_validators would be elected.
+func (p *Preview) CheckMinValidators(params scheduler.ConsensusParameters) error {
+	if len(p.Validators) == 0 {
+		return fmt.Errorf("no validators would be elected")
+	}
+	if len(p.Validators) < params.MinValidators {
+		return fmt.Errorf("%d validators would be elected, min_validators is %d", len(p.Validators), params.MinValidators)
+	}
+	return nil
+}
+
+// candidateNodes returns the registered validator nodes of every entity
+// and the entities whose nodes can't be registered. Nodes are ordered by
+// ID, the scheduler shuffles them with the beacon.
+func candidateNodes(reg *registry.Genesis) (map[signature.PublicKey][]*node.Node, map[signature.PublicKey]string, error) {
+	entities := make(map[signature.PublicKey]bool)
+	for _, signedEntity := range reg.Entities {
+		var ent entity.Entity
+		if err := signedEntity.Open(registry.RegisterGenesisEntitySignatureContext, &ent); err != nil {
+			return nil, nil, fmt.Errorf("registry entity does not verify: %w", err)
+		}
+		entities[ent.ID] = true
+	}
+
+	nodes := make(map[signature.PublicKey][]*node.Node)
+	problems := make(map[signature.PublicKey]string)
+	for _, signedNode := range reg.Nodes {
+		var n node.Node
+		if err := signedNode.Open(registry.RegisterGenesisNodeSignatureContext, &n); err != nil {
+			return nil, nil, fmt.Errorf("registry node does not verify: %w", err)
+		}
+		if !entities[n.EntityID] {
+			problems[n.EntityID] = ReasonUnregisteredEntity
+			continue
+		}
+		if !n.HasRoles(node.RoleValidator) {
+			continue
+		}
+		nodes[n.EntityID] = append(nodes[n.EntityID], &n)
+	}
+	for _, entityNodes := range nodes {
+		sort.Slice(entityNodes, func(i, j int) bool {
+			return bytes.Compare(entityNodes[i].ID[:], entityNodes[j].ID[:]) < 0
+		})
+	}
+	for entityID := range entities {
+		if _, ok := nodes[entityID]; !ok && problems[entityID] == "" {
+			problems[entityID] = ReasonNoValidatorNode
+		}
+	}
+	return nodes, problems, nil
+}
+
+// checkStakeClaims adds the stake claims the registry adds when an entity and
+// its nodes are registered at genesis to a copy of the entity's escrow
+// account. An error means the escrow doesn't cover them.
+func checkStakeClaims(stakingGenesis *staking.Genesis, address staking.Address, nodes []*node.Node) error {
+	escrow := staking.EscrowAccount{}
+	if account := stakingGenesis.Ledger[address]; account != nil {
+		escrow.Active.Balance = account.Escrow.Active.Balance
+		escrow.Active.TotalShares = account.Escrow.Active.TotalShares
+	}
+
+	thresholds := stakingGenesis.Parameters.Thresholds
+	if err := escrow.AddStakeClaim(thresholds, registry.StakeClaimRegisterEntity,
+		staking.GlobalStakeThresholds(staking.KindEntity)); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if err := escrow.AddStakeClaim(thresholds, registry.StakeClaimForNode(n.ID),
+			registry.StakeThresholdsForNode(n, nil)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Elect computes the initial validator set from the staking ledger, the
+// registry and the scheduler parameters the same way the scheduler does:
+// entities with a registered validator node whose escrow covers their stake
+// claims are ranked by escrow and up to max_validators_per_entity nodes are
+// picked from each until max_validators is reached.
+func Elect(options Options) (*Preview, error) {
+	params := options.Parameters
+	if params.MaxValidators <= 0 {
+		return nil, fmt.Errorf("max_validators is not configured")
+	}
+	if params.MaxValidatorsPerEntity <= 0 {
+		return nil, fmt.Errorf("max_validators_per_entity is not configured")
+	}
+
+	nodes, problems, err := candidateNodes(options.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{}
+	var eligible []*Candidate
+	for entityID, reason := range problems {
+		preview.Missed = append(preview.Missed, newCandidate(options.Staking, entityID, reason))
+	}
+	for entityID, entityNodes := range nodes {
+		candidate := newCandidate(options.Staking, entityID, "")
+		if err = checkStakeClaims(options.Staking, candidate.Address, entityNodes); err != nil {
+			candidate.Reason = ReasonInsufficientStake
+			preview.Missed = append(preview.Missed, candidate)
+			continue
+		}
+		eligible = append(eligible, candidate)
+	}
+	sortByEscrow(eligible)
+
+	var cut *Candidate
+	for _, candidate := range eligible {
+		if len(preview.Validators) >= params.MaxValidators {
+			candidate.Reason = ReasonMaxValidators
+			preview.Missed = append(preview.Missed, candidate)
+			if cut != nil && candidate.Escrow.Cmp(&cut.Escrow) == 0 {
+				preview.TiedAtCut = true
+			}
+			continue
+		}
+
+		power, err := scheduler.VotingPowerFromStake(&candidate.Escrow)
+		if err != nil {
+			return nil, fmt.Errorf("computing voting power for account %s: %w", candidate.Address, err)
+		}
+		entityNodes := nodes[candidate.EntityID]
+		for i := 0; i < params.MaxValidatorsPerEntity && i < len(entityNodes); i++ {
+			validator := *candidate
+			validator.NodeID = entityNodes[i].ID
+			validator.ConsensusID = entityNodes[i].Consensus.ID
+			validator.VotingPower = power
+			preview.Validators = append(preview.Validators, &validator)
+			preview.TotalVotingPower += power
+			cut = &validator
+			if len(preview.Validators) >= params.MaxValidators {
+				break
+			}
+		}
+	}
+	sortByEscrow(preview.Missed)
+
+	return preview, nil
+}
+
+func newCandidate(stakingGenesis *staking.Genesis, entityID signature.PublicKey, reason string) *Candidate {
+	candidate := &Candidate{
+		EntityID: entityID,
+		Address:  staking.NewAddress(entityID),
+		Reason:   reason,
+	}
+	if account := stakingGenesis.Ledger[candidate.Address]; account != nil {
+		candidate.Escrow = account.Escrow.Active.Balance
+	}
+	return candidate
+}
+
+// sortByEscrow sorts candidates by descending escrow, ties are ordered by
+// address.
+func sortByEscrow(candidates []*Candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if cmp := candidates[i].Escrow.Cmp(&candidates[j].Escrow); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(candidates[i].Address[:], candidates[j].Address[:]) < 0
+	})
+}
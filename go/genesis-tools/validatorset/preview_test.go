@@ -0,0 +1,190 @@
+package validatorset_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/mainnet-entities/go/genesis-tools/validatorset"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+type testNetwork struct {
+	t        *testing.T
+	staking  *staking.Genesis
+	registry *registry.Genesis
+	ids      map[string]signature.PublicKey
+}
+
+func newTestNetwork(t *testing.T, entityThreshold, validatorThreshold uint64) *testNetwork {
+	return &testNetwork{
+		t: t,
+		staking: &staking.Genesis{
+			Parameters: staking.ConsensusParameters{
+				Thresholds: map[staking.ThresholdKind]quantity.Quantity{
+					staking.KindEntity:        *quantity.NewFromUint64(entityThreshold),
+					staking.KindNodeValidator: *quantity.NewFromUint64(validatorThreshold),
+				},
+			},
+			Ledger: make(map[staking.Address]*staking.Account),
+		},
+		registry: &registry.Genesis{},
+		ids:      make(map[string]signature.PublicKey),
+	}
+}
+
+// addEntity registers an entity with the given escrow and number of nodes
+// with the given roles.
+func (n *testNetwork) addEntity(name string, escrow uint64, nodes int, roles node.RolesMask) {
+	entitySigner := memorySigner.NewTestSigner(name)
+	ent := &entity.Entity{
+		Versioned: cbor.NewVersioned(entity.LatestEntityDescriptorVersion),
+		ID:        entitySigner.Public(),
+	}
+	n.ids[name] = ent.ID
+
+	for i := 0; i < nodes; i++ {
+		nodeSigner := memorySigner.NewTestSigner(fmt.Sprintf("%s/node/%d", name, i))
+		consensusSigner := memorySigner.NewTestSigner(fmt.Sprintf("%s/consensus/%d", name, i))
+		nd := &node.Node{
+			Versioned: cbor.NewVersioned(node.LatestNodeDescriptorVersion),
+			ID:        nodeSigner.Public(),
+			EntityID:  ent.ID,
+			Consensus: node.ConsensusInfo{ID: consensusSigner.Public()},
+			Roles:     roles,
+		}
+		signedNode, err := node.MultiSignNode(
+			[]signature.Signer{nodeSigner, consensusSigner},
+			registry.RegisterGenesisNodeSignatureContext,
+			nd,
+		)
+		require.NoError(n.t, err)
+		ent.Nodes = append(ent.Nodes, nd.ID)
+		n.registry.Nodes = append(n.registry.Nodes, signedNode)
+	}
+
+	signedEntity, err := entity.SignEntity(entitySigner, registry.RegisterGenesisEntitySignatureContext, ent)
+	require.NoError(n.t, err)
+	n.registry.Entities = append(n.registry.Entities, signedEntity)
+
+	account := &staking.Account{}
+	account.Escrow.Active.Balance = *quantity.NewFromUint64(escrow)
+	account.Escrow.Active.TotalShares = *quantity.NewFromUint64(escrow)
+	n.staking.Ledger[staking.NewAddress(ent.ID)] = account
+}
+
+func (n *testNetwork) elect(params scheduler.ConsensusParameters) *validatorset.Preview {
+	preview, err := validatorset.Elect(validatorset.Options{
+		Staking:    n.staking,
+		Registry:   n.registry,
+		Parameters: params,
+	})
+	require.NoError(n.t, err)
+	return preview
+}
+
+func (n *testNetwork) names(candidates []*validatorset.Candidate) []string {
+	byID := make(map[signature.PublicKey]string)
+	for name, id := range n.ids {
+		byID[id] = name
+	}
+	var names []string
+	for _, candidate := range candidates {
+		names = append(names, byID[candidate.EntityID])
+	}
+	return names
+}
+
+func TestElect(t *testing.T) {
+	network := newTestNetwork(t, 100, 100)
+	network.addEntity("small", 300, 1, node.RoleValidator)
+	network.addEntity("large", 3000, 1, node.RoleValidator)
+	network.addEntity("medium", 1000, 1, node.RoleValidator)
+	network.addEntity("poor", 150, 1, node.RoleValidator)
+	network.addEntity("compute", 6000, 1, node.RoleComputeWorker)
+	network.addEntity("no-nodes", 5000, 0, 0)
+
+	preview := network.elect(scheduler.ConsensusParameters{
+		MinValidators:          1,
+		MaxValidators:          2,
+		MaxValidatorsPerEntity: 1,
+	})
+	require.Equal(t, []string{"large", "medium"}, network.names(preview.Validators))
+	require.Equal(t, []string{"compute", "no-nodes", "small", "poor"}, network.names(preview.Missed))
+	require.Equal(t, validatorset.ReasonNoValidatorNode, preview.Missed[0].Reason)
+	require.Equal(t, validatorset.ReasonMaxValidators, preview.Missed[2].Reason)
+	require.Equal(t, validatorset.ReasonInsufficientStake, preview.Missed[3].Reason)
+	require.False(t, preview.TiedAtCut)
+
+	large, err := scheduler.VotingPowerFromStake(quantity.NewFromUint64(3000))
+	require.NoError(t, err)
+	require.Equal(t, large, preview.Validators[0].VotingPower)
+	require.Equal(t, network.ids["large"], preview.Validators[0].EntityID)
+	require.True(t, preview.Validators[0].NodeID.IsValid())
+
+	require.NoError(t, preview.CheckMinValidators(scheduler.ConsensusParameters{MinValidators: 2}))
+	require.Error(t, preview.CheckMinValidators(scheduler.ConsensusParameters{MinValidators: 3}))
+
+	var report bytes.Buffer
+	require.NoError(t, validatorset.WriteReport(&report, preview, nil))
+	require.Contains(t, report.String(), "2 validator(s)")
+	require.Contains(t, report.String(), validatorset.ReasonInsufficientStake)
+}
+
+func TestElectMaxValidatorsPerEntity(t *testing.T) {
+	network := newTestNetwork(t, 0, 0)
+	network.addEntity("multi", 2000, 3, node.RoleValidator)
+	network.addEntity("single", 1000, 1, node.RoleValidator)
+
+	preview := network.elect(scheduler.ConsensusParameters{
+		MaxValidators:          10,
+		MaxValidatorsPerEntity: 1,
+	})
+	require.Equal(t, []string{"multi", "single"}, network.names(preview.Validators))
+
+	preview = network.elect(scheduler.ConsensusParameters{
+		MaxValidators:          3,
+		MaxValidatorsPerEntity: 2,
+	})
+	require.Equal(t, []string{"multi", "multi", "single"}, network.names(preview.Validators))
+	require.NotEqual(t, preview.Validators[0].NodeID, preview.Validators[1].NodeID)
+
+	// Every node of an entity is claimed against its escrow.
+	network = newTestNetwork(t, 0, 600)
+	network.addEntity("multi", 1000, 2, node.RoleValidator)
+	preview = network.elect(scheduler.ConsensusParameters{
+		MaxValidators:          3,
+		MaxValidatorsPerEntity: 2,
+	})
+	require.Empty(t, preview.Validators)
+	require.Error(t, preview.CheckMinValidators(scheduler.ConsensusParameters{}))
+}
+
+func TestElectTiedAtCut(t *testing.T) {
+	network := newTestNetwork(t, 0, 0)
+	network.addEntity("a", 1000, 1, node.RoleValidator)
+	network.addEntity("b", 1000, 1, node.RoleValidator)
+
+	preview := network.elect(scheduler.ConsensusParameters{
+		MaxValidators:          1,
+		MaxValidatorsPerEntity: 1,
+	})
+	require.Len(t, preview.Validators, 1)
+	require.True(t, preview.TiedAtCut)
+
+	_, err := validatorset.Elect(validatorset.Options{
+		Staking:  network.staking,
+		Registry: network.registry,
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package validatorset
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// WriteReport writes the validators and the entities that missed the cut as
+// tables. names maps entity IDs to package names and may be nil.
+func WriteReport(w io.Writer, preview *Preview, names map[signature.PublicKey]string) error {
+	name := func(c *Candidate) string {
+		if n, ok := names[c.EntityID]; ok {
+			return n
+		}
+		return c.EntityID.String()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d validator(s), total voting power %d\n", len(preview.Validators), preview.TotalVotingPower)
+	fmt.Fprintln(tw, "#\tentity\taddress\tescrow\tvoting power\tshare")
+	for i, validator := range preview.Validators {
+		var share float64
+		if preview.TotalVotingPower > 0 {
+			share = 100 * float64(validator.VotingPower) / float64(preview.TotalVotingPower)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%.2f%%\n",
+			i+1, name(validator), validator.Address, validator.Escrow.String(), validator.VotingPower, share)
+	}
+	if preview.TiedAtCut {
+		fmt.Fprintln(tw, "warning: the last validator is tied with an entity below the cut, the beacon decides between them")
+	}
+
+	if len(preview.Missed) > 0 {
+		fmt.Fprintf(tw, "\n%d entit(ies) miss the cut\n", len(preview.Missed))
+		fmt.Fprintln(tw, "entity\taddress\tescrow\treason")
+		for _, missed := range preview.Missed {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name(missed), missed.Address, missed.Escrow.String(), missed.Reason)
+		}
+	}
+	return tw.Flush()
+}